@@ -1,20 +1,30 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/LoganTackett1/brainstorming-backend/internal/awsclient"
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
 	"github.com/LoganTackett1/brainstorming-backend/internal/boardaccess"
 	"github.com/LoganTackett1/brainstorming-backend/internal/boarddetail"
 	"github.com/LoganTackett1/brainstorming-backend/internal/card"
 	"github.com/LoganTackett1/brainstorming-backend/internal/db"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+	"github.com/LoganTackett1/brainstorming-backend/internal/gc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/importer"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/presence"
+	"github.com/LoganTackett1/brainstorming-backend/internal/realtime"
+	"github.com/LoganTackett1/brainstorming-backend/internal/search"
 	"github.com/LoganTackett1/brainstorming-backend/internal/share"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+	"github.com/LoganTackett1/brainstorming-backend/internal/thumbnail"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
 
 	"github.com/joho/godotenv"
@@ -33,31 +43,91 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// --- AWS S3 ---
-	s3Client := awsclient.NewS3Client()
-	bucket := os.Getenv("S3_BUCKET")
-	thumbnailHandler := &board.ThumbnailHandler{DB: database, S3Client: s3Client, Bucket: bucket}
+	// --- Blob storage (S3 or local disk, see storage.NewFromEnv) ---
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage.SetSigner(store) // cards and boards hand out signed URLs for stored keys
+	if local, ok := store.(*storage.LocalStore); ok {
+		http.Handle(storage.FilesPrefix, storage.Handler(local)) // GET/PUT /files/{key}?expires=&sig=
+	}
+	thumbnailHandler := &board.ThumbnailHandler{DB: database, Store: store}
+
+	// --- Orphaned image sweeper (see also cmd/gc) ---
+	// IMAGE_GC_INTERVAL (default 6h, "0" disables), IMAGE_GC_GRACE (default 24h)
+	// and IMAGE_GC_HISTORY (default 720h, "0" keeps images history needs forever)
+	gcInterval, gcOpts := 6*time.Hour, gc.Options{Grace: gc.DefaultGrace, History: gc.DefaultHistory}
+	if v := os.Getenv("IMAGE_GC_INTERVAL"); v != "" {
+		if gcInterval, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid IMAGE_GC_INTERVAL: %v", err)
+		}
+	}
+	if v := os.Getenv("IMAGE_GC_GRACE"); v != "" {
+		if gcOpts.Grace, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid IMAGE_GC_GRACE: %v", err)
+		}
+	}
+	if v := os.Getenv("IMAGE_GC_HISTORY"); v != "" {
+		if gcOpts.History, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid IMAGE_GC_HISTORY: %v", err)
+		}
+	}
+	if gcInterval > 0 {
+		go gc.Run(context.Background(), database, store, gcInterval, gcOpts)
+	}
 
 	// Image upload handlers
-	boardImageUpload := card.NewBoardImageUploadHandler(database)  // POST /boards/{id}/images (authed owner/edit)
-	shareImageUpload := share.NewShareImageUploadHandler(database) // POST /share/{token}/images (share token, edit)
+	boardImageUpload := card.NewBoardImageUploadHandler(database, store)  // POST /boards/{id}/images (authed owner/edit)
+	shareImageUpload := share.NewShareImageUploadHandler(database, store) // POST /share/{token}/images (share token, edit)
+
+	// --- Realtime hub (card events + presence -> websockets) ---
+	tracker := presence.NewTracker()
+	hub := realtime.NewHub(tracker)
+
+	// --- Server-rendered thumbnails, redrawn once card changes settle ---
+	// THUMBNAIL_SETTLE (default 30s, "0" disables)
+	settle := thumbnail.DefaultSettle
+	if v := os.Getenv("THUMBNAIL_SETTLE"); v != "" {
+		if settle, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid THUMBNAIL_SETTLE: %v", err)
+		}
+	}
+	if settle > 0 {
+		card.SetPublisher(card.Publishers{hub, thumbnail.NewRenderer(database, store, settle)})
+	} else {
+		card.SetPublisher(hub)
+	}
+	boardSocket := &realtime.BoardSocketHandler{DB: database, Hub: hub}          // GET /boards/{id}/ws
+	shareSocket := &realtime.ShareSocketHandler{DB: database, Hub: hub}          // GET /share/{token}/ws
+	presenceHandler := &presence.PresenceHandler{DB: database, Tracker: tracker} // GET /boards/{id}/presence
 
 	// --- User Routes ---
 	signupHandler := &user.SignupHandler{DB: database}
 	loginHandler := &user.LoginHandler{DB: database}
 	meHandler := &user.MeHandler{DB: database}
+	usageHandler := &user.UsageHandler{DB: database}
 	emailHandler := &user.EmailHandler{DB: database}
 	http.Handle("/signup", signupHandler)
 	http.Handle("/login", loginHandler)
 	http.Handle("/me", user.AuthMiddleware(meHandler))
+	http.Handle("/me/usage", user.AuthMiddleware(usageHandler))
 	http.Handle("/emailToID", user.AuthMiddleware(emailHandler))
 
+	// --- Search across every board the user can open ---
+	searchHandler := &search.Handler{DB: database}
+	http.Handle("/search", user.AuthMiddleware(searchHandler)) // GET /search?q=
+
 	// --- Board Routes ---
 	boardHandler := &board.BoardHandler{DB: database}
 	http.Handle("/boards", user.AuthMiddleware(boardHandler)) // exact match only
 
 	// --- Card Routes ---
-	cardOnlyHandler := &card.CardOnlyHandler{DB: database, S3Client: s3Client, Bucket: bucket}
+	cardOnlyHandler := &card.CardOnlyHandler{DB: database, Store: store}
+	cardBatchHandler := &card.BatchHandler{DB: database}                 // POST /boards/{id}/cards:batch
+	exportHandler := &export.BoardHandler{DB: database, Store: store}    // GET /boards/{id}/export.{format}
+	importHandler := &importer.ImportHandler{DB: database, Store: store} // POST /boards/import
+	csvImportHandler := &importer.CSVHandler{DB: database}               // POST /boards/{id}/import/csv
 	http.Handle("/cards/", user.AuthMiddleware(cardOnlyHandler))
 
 	// --- Permission Route for Share Links ---
@@ -69,11 +139,45 @@ func main() {
 		path := r.URL.Path
 
 		switch {
+		case path == "/boards/import":
+			importHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/import/csv"):
+			csvImportHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/cards:batch"):
+			cardBatchHandler.ServeHTTP(w, r)
+			return
+
 		case strings.HasSuffix(path, "/cards"):
 			cardHandler := &card.CardHandler{DB: database}
 			cardHandler.ServeHTTP(w, r)
 			return
 
+		case strings.HasSuffix(path, "/links") || strings.Contains(path, "/links/"):
+			// /boards/{id}/links and /boards/{id}/links/{linkID}
+			linkHandler := &card.LinkHandler{DB: database}
+			linkHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/snapshots") || strings.Contains(path, "/snapshots/"):
+			// /boards/{id}/snapshots[/{sid}[/restore]]
+			snapshotHandler := &card.SnapshotHandler{DB: database}
+			snapshotHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/undo") || strings.HasSuffix(path, "/redo"):
+			undoHandler := &card.UndoHandler{DB: database}
+			undoHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/activity"):
+			activityHandler := &activity.Handler{DB: database}
+			activityHandler.ServeHTTP(w, r)
+			return
+
 		case strings.HasSuffix(path, "/access"):
 			accessHandler := &boardaccess.BoardAccessHandler{DB: database}
 			accessHandler.ServeHTTP(w, r)
@@ -88,11 +192,25 @@ func main() {
 			thumbnailHandler.ServeHTTP(w, r)
 			return
 
-		case strings.HasSuffix(path, "/images"):
-			// POST /boards/{id}/images
+		case strings.Contains(path, "/export."):
+			// GET /boards/{id}/export.{svg|png|pdf|json|md|opml|excalidraw}
+			exportHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/images") || strings.Contains(path, "/images/"):
+			// POST /boards/{id}/images[/presign|/confirm]
 			boardImageUpload.ServeHTTP(w, r)
 			return
 
+		case strings.HasSuffix(path, "/presence"):
+			presenceHandler.ServeHTTP(w, r)
+			return
+
+		case strings.HasSuffix(path, "/ws"):
+			// GET /boards/{id}/ws (websocket)
+			boardSocket.ServeHTTP(w, r)
+			return
+
 		default:
 			// fallback /boards/{id}
 			detailHandler := &boarddetail.BoardDetailHandler{DB: database}
@@ -102,17 +220,44 @@ func main() {
 	})))
 
 	// --- Share routes ---
-	shareCardHandler := &share.ShareCardHandler{DB: database, S3Client: s3Client, Bucket: bucket}
+	shareCardHandler := &share.ShareCardHandler{DB: database}
+	shareBatchHandler := &share.ShareBatchHandler{DB: database}                 // POST /share/{token}/cards:batch
+	shareExportHandler := &share.ShareExportHandler{DB: database, Store: store} // GET /share/{token}/export.{format}
 
 	http.Handle("/share/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 
-		// POST /share/{token}/images
-		if strings.HasSuffix(path, "/images") {
+		// POST /share/{token}/images[/presign|/confirm]
+		if strings.HasSuffix(path, "/images") || strings.Contains(path, "/images/") {
 			shareImageUpload.ServeHTTP(w, r)
 			return
 		}
 
+		// GET /share/{token}/export.{svg|png|pdf|json|md|opml|excalidraw}
+		if strings.Contains(path, "/export.") {
+			shareExportHandler.ServeHTTP(w, r)
+			return
+		}
+
+		// GET /share/{token}/ws (websocket)
+		if strings.HasSuffix(path, "/ws") {
+			shareSocket.ServeHTTP(w, r)
+			return
+		}
+
+		// POST /share/{token}/cards:batch
+		if strings.HasSuffix(path, "/cards:batch") {
+			shareBatchHandler.ServeHTTP(w, r)
+			return
+		}
+
+		// /share/{token}/links and /share/{token}/links/{id}
+		if strings.HasSuffix(path, "/links") || strings.Contains(path, "/links/") {
+			shareLinkHandler := &share.ShareLinkHandler{DB: database}
+			shareLinkHandler.ServeHTTP(w, r)
+			return
+		}
+
 		// /share/{token}/cards and /share/{token}/cards/{id}
 		if strings.HasSuffix(path, "/cards") || strings.Contains(path, "/cards/") {
 			shareCardHandler.ServeHTTP(w, r)
@@ -0,0 +1,55 @@
+// Command gc removes stored images and thumbnails that no card, board,
+// recent revision or snapshot refers to (see package gc for what is kept).
+// The API runs the same sweep in the background; this is for running it by
+// hand or from cron.
+//
+//	go run ./cmd/gc -dry-run          # list what would be deleted
+//	go run ./cmd/gc -grace 72h        # delete orphans older than three days
+//	go run ./cmd/gc -history 2160h    # keep images history needs for 90 days
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/db"
+	"github.com/LoganTackett1/brainstorming-backend/internal/gc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+
+	dryRun := flag.Bool("dry-run", false, "list orphaned objects without deleting them")
+	grace := flag.Duration("grace", gc.DefaultGrace, "only delete objects older than this")
+	history := flag.Duration("history", gc.DefaultHistory, "keep images card history and automatic snapshots mention for this long (0 = forever)")
+	flag.Parse()
+
+	database, err := db.NewMySQL()
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := gc.Sweep(context.Background(), database, store, gc.Options{Grace: *grace, History: *history, DryRun: *dryRun})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, key := range report.Orphans {
+		fmt.Println(key)
+	}
+	verb := "deleted"
+	if *dryRun {
+		verb = "would delete"
+	}
+	fmt.Printf("scanned %d objects, %s %d orphans (%d bytes), %d failures\n",
+		report.Scanned, verb, len(report.Orphans), report.Bytes, report.Failed)
+}
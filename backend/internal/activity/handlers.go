@@ -0,0 +1,152 @@
+package activity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+type Handler struct {
+	DB *sql.DB
+}
+
+// GET /boards/{id}/activity
+// perms: owner only
+//
+// Query params (all optional):
+//   - actor_user_id, actor_share_id
+//   - type: comma-separated event types
+//   - since, until: RFC 3339 timestamps
+//   - before: event ID cursor from a previous page's next_before
+//   - limit: page size (default 50, max 200)
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /boards/{id}/activity
+	if len(parts) != 4 || parts[1] != "boards" || parts[3] != "activity" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	// Verify board ownership
+	var count int
+	err = h.DB.QueryRow("SELECT COUNT(*) FROM boards WHERE id = ? AND owner_id = ?", boardID, userID).Scan(&count)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	f, errMsg := parseFilter(r)
+	if errMsg != "" {
+		middleware.JSONError(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	events, err := List(h.DB, boardID, f)
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch activity", http.StatusInternalServerError)
+		return
+	}
+
+	// A full page means there may be more; pass next_before back as ?before=
+	var next *int64
+	if len(events) == f.Limit {
+		next = &events[len(events)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events":      events,
+		"next_before": next,
+	})
+}
+
+func parseFilter(r *http.Request) (Filter, string) {
+	q := r.URL.Query()
+	f := Filter{Limit: defaultLimit}
+
+	ids := []struct {
+		param string
+		dest  *int64
+	}{
+		{"actor_user_id", &f.ActorUserID},
+		{"actor_share_id", &f.ActorShareID},
+		{"before", &f.Before},
+	}
+	for _, id := range ids {
+		if v := q.Get(id.param); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				return Filter{}, "Invalid " + id.param
+			}
+			*id.dest = n
+		}
+	}
+
+	if v := q.Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				f.Types = append(f.Types, t)
+			}
+		}
+	}
+
+	times := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"since", &f.Since},
+		{"until", &f.Until},
+	}
+	for _, t := range times {
+		if v := q.Get(t.param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return Filter{}, "Invalid " + t.param + " (expected RFC 3339)"
+			}
+			*t.dest = parsed
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return Filter{}, "Invalid limit"
+		}
+		if n > maxLimit {
+			n = maxLimit
+		}
+		f.Limit = n
+	}
+
+	return f, ""
+}
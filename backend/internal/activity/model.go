@@ -0,0 +1,151 @@
+package activity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+// Event types
+const (
+	TypeCardCreated      = "card.created"
+	TypeCardDeleted      = "card.deleted"
+	TypeAccessGranted    = "access.granted"
+	TypeAccessUpdated    = "access.updated"
+	TypeAccessRevoked    = "access.revoked"
+	TypeShareCreated     = "share.created"
+	TypeShareDeleted     = "share.deleted"
+	TypeThumbnailUpdated = "thumbnail.updated"
+	TypeThumbnailDeleted = "thumbnail.deleted"
+	TypeBoardRenamed     = "board.renamed"
+	TypeBoardRestored    = "board.restored"
+	TypeBoardImported    = "board.imported"
+)
+
+// Event is one entry in a board's append-only activity log. Exactly one of
+// ActorUserID and ActorShareID is set, except for system actions.
+type Event struct {
+	ID           int64           `json:"id"`
+	BoardID      int64           `json:"board_id"`
+	Type         string          `json:"type"`
+	ActorUserID  *int64          `json:"actor_user_id"`
+	ActorShareID *int64          `json:"actor_share_id"`
+	Data         json.RawMessage `json:"data,omitempty"` // type-specific details
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Execer is satisfied by *sql.DB and *sql.Tx, so events can be written as
+// part of the transaction that made the change
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Record appends an event to a board's log. data is encoded as JSON; nil
+// stores no details.
+func Record(db Execer, boardID int64, userID, shareID *int64, eventType string, data interface{}) error {
+	var payload interface{}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+	_, err := db.Exec(
+		"INSERT INTO board_events (board_id, type, actor_user_id, actor_share_id, data) VALUES (?, ?, ?, ?, ?)",
+		boardID, eventType, userID, shareID, payload,
+	)
+	return err
+}
+
+// Log is Record for handlers whose change has already been committed: a
+// failure to log is reported but does not fail the request
+func Log(db Execer, boardID int64, userID, shareID *int64, eventType string, data interface{}) {
+	if err := Record(db, boardID, userID, shareID, eventType, data); err != nil {
+		log.Printf("WARN: failed to record %s on board %d: %v", eventType, boardID, err)
+	}
+}
+
+// Filter narrows a board's activity; zero values mean "no filter"
+type Filter struct {
+	ActorUserID  int64
+	ActorShareID int64
+	Types        []string
+	Since        time.Time // inclusive
+	Until        time.Time // exclusive
+	Before       int64     // event ID cursor: only older events
+	Limit        int
+}
+
+// List returns a board's events newest first
+func List(db *sql.DB, boardID int64, f Filter) ([]Event, error) {
+	where := []string{"board_id = ?"}
+	args := []interface{}{boardID}
+
+	if f.ActorUserID != 0 {
+		where = append(where, "actor_user_id = ?")
+		args = append(args, f.ActorUserID)
+	}
+	if f.ActorShareID != 0 {
+		where = append(where, "actor_share_id = ?")
+		args = append(args, f.ActorShareID)
+	}
+	if len(f.Types) > 0 {
+		where = append(where, "type IN (?"+strings.Repeat(", ?", len(f.Types)-1)+")")
+		for _, t := range f.Types {
+			args = append(args, t)
+		}
+	}
+	// FROM_UNIXTIME keeps the comparison in the session time zone, like the column
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= FROM_UNIXTIME(?)")
+		args = append(args, float64(f.Since.UnixMicro())/1e6)
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_at < FROM_UNIXTIME(?)")
+		args = append(args, float64(f.Until.UnixMicro())/1e6)
+	}
+	if f.Before != 0 {
+		where = append(where, "id < ?")
+		args = append(args, f.Before)
+	}
+	args = append(args, f.Limit)
+
+	rows, err := db.Query(
+		"SELECT id, board_id, type, actor_user_id, actor_share_id, data, created_at FROM board_events WHERE "+
+			strings.Join(where, " AND ")+" ORDER BY id DESC LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var userID, shareID sql.NullInt64
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.BoardID, &e.Type, &userID, &shareID, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			e.ActorUserID = &userID.Int64
+		}
+		if shareID.Valid {
+			e.ActorShareID = &shareID.Int64
+		}
+		if data != nil {
+			e.Data = json.RawMessage(data)
+		}
+		events = append(events, e)
+	}
+
+	if events == nil {
+		events = []Event{}
+	}
+
+	return events, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
 )
@@ -59,10 +60,24 @@ func (h *BoardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
+		// Old title for the activity log
+		var oldTitle string
+		err := h.DB.QueryRow("SELECT title FROM boards WHERE id = ? AND owner_id = ?", body.ID, userID).Scan(&oldTitle)
+		owned := err == nil
+		if err != nil && err != sql.ErrNoRows {
+			middleware.JSONError(w, "Failed to update board", http.StatusInternalServerError)
+			return
+		}
 		if err := UpdateBoard(h.DB, body.ID, userID, body.Title); err != nil {
 			middleware.JSONError(w, "Failed to update board", http.StatusInternalServerError)
 			return
 		}
+		if owned && oldTitle != body.Title {
+			activity.Log(h.DB, body.ID, &userID, nil, activity.TypeBoardRenamed, map[string]interface{}{
+				"from": oldTitle,
+				"to":   body.Title,
+			})
+		}
 		json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 
 	case http.MethodDelete:
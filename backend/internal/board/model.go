@@ -1,8 +1,16 @@
 package board
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"fmt"
+	"log"
 	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
 )
 
 type Board struct {
@@ -52,6 +60,7 @@ func GetBoards(db *sql.DB, userID int64) ([]Board, error) {
 			return nil, err
 		}
 		b.IsOwner = (b.Permission == "owner")
+		b.ThumbnailURL = storage.Sign(b.ThumbnailURL)
 		boards = append(boards, b)
 	}
 
@@ -68,11 +77,73 @@ func UpdateBoard(db *sql.DB, boardID, ownerID int64, title string) error {
 	return err
 }
 
-// DeleteBoard deletes a board if it belongs to the user
+// DeleteBoard deletes a board if it belongs to the user. Its cards go with it
+// by cascade, so the images they used are released here; the files
+// themselves are left to the storage sweeper.
 func DeleteBoard(db *sql.DB, boardID, ownerID int64) (int64, error) {
-	res, err := db.Exec("DELETE FROM boards WHERE id = ? AND owner_id = ?", boardID, ownerID)
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`UPDATE image_objects o
+         JOIN (
+             SELECT c.image_url, COUNT(*) AS n FROM cards c
+             JOIN boards b ON b.id = c.board_id
+             WHERE c.board_id = ? AND b.owner_id = ? AND c.kind = 'image'
+             GROUP BY c.image_url
+         ) used ON used.image_url = o.storage_key
+         SET o.ref_count = GREATEST(o.ref_count - used.n, 0)`,
+		boardID, ownerID,
+	)
 	if err != nil {
 		return 0, err
 	}
-	return res.RowsAffected()
+
+	res, err := tx.Exec("DELETE FROM boards WHERE id = ? AND owner_id = ?", boardID, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return affected, err
+	}
+	return affected, tx.Commit()
+}
+
+// StoreThumbnail saves img as the board's thumbnail under thumbnails/{id}{ext},
+// replacing the previous one, and returns the key. Returns sql.ErrNoRows if
+// the board is gone and quota.ErrQuotaExceeded if it does not fit.
+func StoreThumbnail(ctx context.Context, db *sql.DB, store storage.Store, boardID int64, img imageproc.Image) (string, error) {
+	var oldKey sql.NullString
+	if err := db.QueryRow("SELECT thumbnail_url FROM boards WHERE id = ?", boardID).Scan(&oldKey); err != nil {
+		return "", err
+	}
+	size := int64(len(img.Data))
+	if err := quota.CheckThumbnail(db, boardID, size); err != nil {
+		return "", err
+	}
+
+	// Upload (replaces old file if key already exists)
+	key := fmt.Sprintf("thumbnails/%d%s", boardID, img.Ext)
+	if err := store.Put(ctx, key, bytes.NewReader(img.Data), img.ContentType); err != nil {
+		return "", err
+	}
+	// Save the key to DB; clients only ever see signed URLs
+	if _, err := db.Exec("UPDATE boards SET thumbnail_url = ? WHERE id = ?", key, boardID); err != nil {
+		return "", err
+	}
+	if err := quota.SetThumbnailBytes(db, boardID, size); err != nil {
+		log.Printf("Thumbnail quota update error: %v", err)
+	}
+
+	// A thumbnail in another format was stored under a different key
+	if oldKey.Valid && oldKey.String != key {
+		if k, ok := storage.KeyFromURL(oldKey.String, "thumbnails/"); ok {
+			store.Delete(ctx, k)
+		}
+	}
+	return key, nil
 }
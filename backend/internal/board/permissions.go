@@ -56,3 +56,23 @@ func GetSharePermission(db *sql.DB, token string) (int64, string, error) {
 
 	return boardID, perm, nil
 }
+
+// GetShareLink is GetSharePermission plus the share link's own ID, for
+// attributing changes made through the link
+func GetShareLink(db *sql.DB, token string) (int64, int64, string, error) {
+	var shareID, boardID int64
+	var perm string
+
+	err := db.QueryRow(
+		"SELECT id, board_id, permission FROM board_shares WHERE token = ?",
+		token,
+	).Scan(&shareID, &boardID, &perm)
+	if err == sql.ErrNoRows {
+		return 0, 0, PermissionNone, nil
+	}
+	if err != nil {
+		return 0, 0, PermissionNone, err
+	}
+
+	return shareID, boardID, perm, nil
+}
@@ -1,25 +1,29 @@
 package board
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"strconv"
 
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// maxThumbnailBytes caps an uploaded thumbnail; larger files are rejected
+// rather than cut short
+const maxThumbnailBytes = 10 << 20
+
 type ThumbnailHandler struct {
-	DB       *sql.DB
-	S3Client *s3.Client
-	Bucket   string
+	DB    *sql.DB
+	Store storage.Store
 }
 
 func (h *ThumbnailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -32,14 +36,20 @@ func (h *ThumbnailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
 		// Parse form with a file
-		err := r.ParseMultipartForm(10 << 20) // 10 MB limit
+		r.Body = http.MaxBytesReader(w, r.Body, maxThumbnailBytes+1<<20) // room for the other fields
+		err := r.ParseMultipartForm(maxThumbnailBytes)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			middleware.JSONError(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		if err != nil {
 			log.Printf("Form parse error: %v", err)
 			middleware.JSONError(w, "Invalid form data", http.StatusBadRequest)
 			return
 		}
 
-		file, handler, err := r.FormFile("file")
+		file, _, err := r.FormFile("file")
 		if err != nil {
 			middleware.JSONError(w, "Missing file", http.StatusBadRequest)
 			return
@@ -51,6 +61,11 @@ func (h *ThumbnailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Missing board_id", http.StatusBadRequest)
 			return
 		}
+		bid, err := strconv.ParseInt(boardID, 10, 64)
+		if err != nil {
+			middleware.JSONError(w, "Invalid board_id", http.StatusBadRequest)
+			return
+		}
 
 		// Verify board ownership
 		var count int
@@ -60,29 +75,46 @@ func (h *ThumbnailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Create S3 key
-		key := fmt.Sprintf("thumbnails/%s%s", boardID, filepath.Ext(handler.Filename))
+		// Check it's a real image and drop any metadata; the extension comes
+		// from the content, not the client's filename
+		data, err := io.ReadAll(io.LimitReader(file, maxThumbnailBytes+1))
+		if err != nil {
+			middleware.JSONError(w, "Failed to read file", http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxThumbnailBytes {
+			middleware.JSONError(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		img, err := imageproc.Process(data)
+		if err == imageproc.ErrUnsupported || err == imageproc.ErrTooLarge {
+			middleware.JSONError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			log.Printf("Thumbnail processing error: %v", err)
+			middleware.JSONError(w, "Failed to process thumbnail", http.StatusInternalServerError)
+			return
+		}
 
-		// Upload to S3 (replaces old file if key already exists)
-		_, err = h.S3Client.PutObject(context.TODO(), &s3.PutObjectInput{
-			Bucket: &h.Bucket,
-			Key:    &key,
-			Body:   file,
-		})
+		key, err := StoreThumbnail(r.Context(), h.DB, h.Store, bid, img.Original)
+		if err == quota.ErrQuotaExceeded {
+			middleware.JSONError(w, err.Error(), http.StatusPaymentRequired)
+			return
+		}
 		if err != nil {
-			log.Printf("S3 upload error: %v", err)
-			middleware.JSONError(w, "Failed to upload to S3", http.StatusInternalServerError)
+			log.Printf("Thumbnail upload error: %v", err)
+			middleware.JSONError(w, "Failed to upload thumbnail", http.StatusInternalServerError)
 			return
 		}
 
-		// Construct URL
-		url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
-			h.Bucket, os.Getenv("AWS_REGION"), key)
+		activity.Log(h.DB, bid, &userID, nil, activity.TypeThumbnailUpdated, map[string]interface{}{
+			"key": key,
+		})
 
-		// Save to DB
-		_, err = h.DB.Exec("UPDATE boards SET thumbnail_url = ? WHERE id = ? AND owner_id = ?", url, boardID, userID)
+		url, err := h.Store.SignedURL(r.Context(), key, storage.URLTTL)
 		if err != nil {
-			middleware.JSONError(w, "Failed to update board", http.StatusInternalServerError)
+			middleware.JSONError(w, "Failed to sign thumbnail URL", http.StatusInternalServerError)
 			return
 		}
 
@@ -95,10 +127,15 @@ func (h *ThumbnailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Missing board_id", http.StatusBadRequest)
 			return
 		}
+		bid, err := strconv.ParseInt(boardID, 10, 64)
+		if err != nil {
+			middleware.JSONError(w, "Invalid board_id", http.StatusBadRequest)
+			return
+		}
 
 		// Verify board ownership
 		var key string
-		err := h.DB.QueryRow("SELECT thumbnail_url FROM boards WHERE id = ? AND owner_id = ?", boardID, userID).Scan(&key)
+		err = h.DB.QueryRow("SELECT thumbnail_url FROM boards WHERE id = ? AND owner_id = ?", boardID, userID).Scan(&key)
 		if err == sql.ErrNoRows {
 			middleware.JSONError(w, "Board not found or not owned by user", http.StatusForbidden)
 			return
@@ -113,17 +150,15 @@ func (h *ThumbnailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Failed to update board", http.StatusInternalServerError)
 			return
 		}
+		activity.Log(h.DB, bid, &userID, nil, activity.TypeThumbnailDeleted, nil)
+		if err := quota.SetThumbnailBytes(h.DB, bid, 0); err != nil {
+			log.Printf("Thumbnail quota update error: %v", err)
+		}
 
-		// Delete from S3 if URL exists
-		if key != "" {
-			// Extract key from URL (after bucket hostname)
-			s3Key := key[strings.Index(key, "thumbnails/"):]
-			_, err = h.S3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-				Bucket: &h.Bucket,
-				Key:    &s3Key,
-			})
-			if err != nil {
-				middleware.JSONError(w, "Failed to delete from S3", http.StatusInternalServerError)
+		// Delete the stored file if the URL points at one
+		if storageKey, ok := storage.KeyFromURL(key, "thumbnails/"); ok {
+			if err := h.Store.Delete(r.Context(), storageKey); err != nil {
+				middleware.JSONError(w, "Failed to delete thumbnail file", http.StatusInternalServerError)
 				return
 			}
 		}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
@@ -83,6 +84,10 @@ func (h *BoardAccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Failed to grant access", http.StatusInternalServerError)
 			return
 		}
+		activity.Log(h.DB, boardID, &userID, nil, activity.TypeAccessGranted, map[string]interface{}{
+			"user_id":    body.UserID,
+			"permission": body.Permission,
+		})
 
 		json.NewEncoder(w).Encode(map[string]string{"status": "granted"})
 
@@ -114,6 +119,12 @@ func (h *BoardAccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Failed to update access", http.StatusInternalServerError)
 			return
 		}
+		if res > 0 {
+			activity.Log(h.DB, boardID, &userID, nil, activity.TypeAccessUpdated, map[string]interface{}{
+				"user_id":    body.UserID,
+				"permission": body.Permission,
+			})
+		}
 
 		json.NewEncoder(w).Encode(map[string]string{"status": "updated access"})
 
@@ -140,6 +151,9 @@ func (h *BoardAccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "No access entry found", http.StatusNotFound)
 			return
 		}
+		activity.Log(h.DB, boardID, &userID, nil, activity.TypeAccessRevoked, map[string]interface{}{
+			"user_id": body.UserID,
+		})
 
 		json.NewEncoder(w).Encode(map[string]string{"status": "revoked"})
 
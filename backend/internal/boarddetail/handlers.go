@@ -75,6 +75,13 @@ func (h *BoardDetailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Cursor first, so changes made while we read show up in the next ?since= fetch
+	cursor, err := card.CurrentCursor(h.DB)
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch cards", http.StatusInternalServerError)
+		return
+	}
+
 	// Fetch cards
 	cards, err := card.GetCardsByBoard(h.DB, boardID)
 	if err != nil {
@@ -82,6 +89,13 @@ func (h *BoardDetailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch links between cards
+	links, err := card.GetLinksByBoard(h.DB, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch links", http.StatusInternalServerError)
+		return
+	}
+
 	// Response payload
 	response := map[string]interface{}{
 		"id":         b.ID,
@@ -89,6 +103,8 @@ func (h *BoardDetailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"owner_id":   b.OwnerID,
 		"permission": perm,
 		"cards":      cards,
+		"links":      links,
+		"cursor":     cursor,
 		"created_at": b.CreatedAt,
 	}
 
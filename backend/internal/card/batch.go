@@ -0,0 +1,202 @@
+package card
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Batch operation kinds
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// MaxBatchOps caps how many operations a single batch may carry
+const MaxBatchOps = 500
+
+// MaxBatchBytes caps the size of a batch request body, checked while it is
+// read so oversized batches are refused before they are decoded
+const MaxBatchBytes = 4 << 20
+
+// BatchOp is one create/update/delete in a batch request
+type BatchOp struct {
+	Op string `json:"op"` // "create" | "update" | "delete"
+	ID int64  `json:"id,omitempty"`
+
+	// create only
+	Kind     string `json:"kind,omitempty"` // "text" | "image" (default: "text")
+	ImageURL string `json:"image_url,omitempty"`
+
+	Patch
+	Version *int64 `json:"version,omitempty"` // update only: expected version
+}
+
+// BatchResult reports what happened to one operation
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status string `json:"status"` // "created" | "updated" | "deleted" | "failed" | "rolled_back"
+	ID     int64  `json:"id,omitempty"`
+	Card   *Card  `json:"card,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchError describes the operation that aborted a batch
+type BatchError struct {
+	Index   int
+	Status  int // HTTP status for the whole request
+	Message string
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
+}
+
+// ApplyBatch runs every operation against boardID in one transaction. Either
+// all operations apply, or none do and a *BatchError says which one failed.
+// Batches that delete anything first take a "before_batch" snapshot. Every
+// operation is recorded in the card's history under actor. Callers are
+// responsible for the permission check.
+func ApplyBatch(db *sql.DB, boardID int64, ops []BatchOp, actor Actor) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = BatchResult{Index: i, Op: op.Op}
+	}
+
+	tx, err := Begin(db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, op := range ops {
+		if op.Op == OpDelete {
+			if _, err := CreateSnapshot(tx, boardID, actor.UserID, SnapshotBeforeBatch); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	for i, op := range ops {
+		res, berr := applyBatchOp(tx, boardID, op, actor)
+		if berr != nil {
+			berr.Index = i
+			for j := range results {
+				results[j] = BatchResult{Index: j, Op: ops[j].Op, Status: "rolled_back"}
+			}
+			results[i].Status = "failed"
+			results[i].ID = op.ID
+			results[i].Error = berr.Message
+			return results, berr
+		}
+		res.Index, res.Op = i, op.Op
+		results[i] = res
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func applyBatchOp(tx *Tx, boardID int64, op BatchOp, actor Actor) (BatchResult, *BatchError) {
+	switch op.Op {
+	case OpCreate:
+		var x, y float64
+		if op.PositionX != nil {
+			x = *op.PositionX
+		}
+		if op.PositionY != nil {
+			y = *op.PositionY
+		}
+
+		kind := strings.ToLower(strings.TrimSpace(op.Kind))
+		var create func(tx *Tx) (int64, error)
+		switch kind {
+		case "", "text":
+			text := ""
+			if op.Text != nil {
+				text = *op.Text
+			}
+			create = func(tx *Tx) (int64, error) {
+				return CreateCard(tx, boardID, text, x, y)
+			}
+		case "image":
+			if strings.TrimSpace(op.ImageURL) == "" {
+				return BatchResult{}, &BatchError{Status: http.StatusBadRequest, Message: "image_url is required for kind=image"}
+			}
+			create = func(tx *Tx) (int64, error) {
+				return CreateImageCard(tx, boardID, op.ImageURL, x, y, op.Width, op.Height)
+			}
+		default:
+			return BatchResult{}, &BatchError{Status: http.StatusBadRequest, Message: "invalid kind (must be 'text' or 'image')"}
+		}
+		id, err := reviseTx(tx, actor, RevisionCreate, 0, nil, create)
+		if err == ErrImageNotConfirmed {
+			return BatchResult{}, &BatchError{Status: http.StatusUnprocessableEntity, Message: err.Error()}
+		}
+		if err != nil {
+			return BatchResult{}, &BatchError{Status: http.StatusInternalServerError, Message: "Failed to create card"}
+		}
+		c, err := GetCard(tx, id)
+		if err != nil {
+			return BatchResult{}, &BatchError{Status: http.StatusInternalServerError, Message: "Failed to fetch card"}
+		}
+		return BatchResult{Status: "created", ID: id, Card: &c}, nil
+
+	case OpUpdate:
+		existing, berr := loadBatchCard(tx, boardID, op.ID)
+		if berr != nil {
+			return BatchResult{}, berr
+		}
+		var version int64
+		if op.Version != nil {
+			version = *op.Version
+		}
+		_, err := reviseTx(tx, actor, RevisionUpdate, op.ID, nil, func(tx *Tx) (int64, error) {
+			return PatchCard(tx, existing, op.Patch, version)
+		})
+		if err == ErrVersionConflict {
+			return BatchResult{}, &BatchError{Status: http.StatusConflict, Message: err.Error()}
+		}
+		if err != nil {
+			return BatchResult{}, &BatchError{Status: http.StatusInternalServerError, Message: "Failed to update card"}
+		}
+		c, err := GetCard(tx, op.ID)
+		if err != nil {
+			return BatchResult{}, &BatchError{Status: http.StatusInternalServerError, Message: "Failed to fetch card"}
+		}
+		return BatchResult{Status: "updated", ID: op.ID, Card: &c}, nil
+
+	case OpDelete:
+		if _, berr := loadBatchCard(tx, boardID, op.ID); berr != nil {
+			return BatchResult{}, berr
+		}
+		_, err := reviseTx(tx, actor, RevisionDelete, op.ID, nil, func(tx *Tx) (int64, error) {
+			return DeleteCard(tx, op.ID)
+		})
+		if err != nil {
+			return BatchResult{}, &BatchError{Status: http.StatusInternalServerError, Message: "Failed to delete card"}
+		}
+		return BatchResult{Status: "deleted", ID: op.ID}, nil
+
+	default:
+		return BatchResult{}, &BatchError{Status: http.StatusBadRequest, Message: "invalid op (must be 'create', 'update' or 'delete')"}
+	}
+}
+
+// loadBatchCard fetches a card and makes sure it belongs to the batch's board
+func loadBatchCard(tx *Tx, boardID, cardID int64) (Card, *BatchError) {
+	c, err := GetCard(tx, cardID)
+	if err == sql.ErrNoRows || (err == nil && c.BoardID != boardID) {
+		return Card{}, &BatchError{Status: http.StatusNotFound, Message: "Card not found"}
+	}
+	if err != nil {
+		return Card{}, &BatchError{Status: http.StatusInternalServerError, Message: "Failed to fetch card"}
+	}
+	return c, nil
+}
@@ -0,0 +1,106 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+type BatchHandler struct {
+	DB *sql.DB
+}
+
+type batchReq struct {
+	Ops []BatchOp `json:"ops"`
+}
+
+// POST /boards/{id}/cards:batch
+// perms: owner or edit
+func (h *BatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /boards/{id}/cards:batch
+	if len(parts) < 4 || parts[1] != "boards" || parts[3] != "cards:batch" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	// One permission check covers every operation in the batch
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm != board.PermissionOwner && perm != board.PermissionEdit {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	ServeBatch(w, r, h.DB, boardID, UserActor(userID))
+}
+
+// ServeBatch decodes a batch request, applies it and writes per-operation
+// results. Shared by the board and share-link routes after their own auth.
+func ServeBatch(w http.ResponseWriter, r *http.Request, db *sql.DB, boardID int64, actor Actor) {
+	var body batchReq
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBatchBytes)
+	err := json.NewDecoder(r.Body).Decode(&body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		middleware.JSONError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(body.Ops) == 0 {
+		middleware.JSONError(w, "ops must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(body.Ops) > MaxBatchOps {
+		middleware.JSONError(w, "Too many operations in batch", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results, err := ApplyBatch(db, boardID, body.Ops, actor)
+	if berr, ok := err.(*BatchError); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(berr.Status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   berr.Error(),
+			"results": results,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("batch on board %d failed: %v", boardID, err)
+		middleware.JSONError(w, "Failed to apply batch", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
@@ -0,0 +1,159 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+)
+
+// ErrInvalidCursor is returned for a ?since= value we did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorWindow is how far before "now" a cursor points. Rows are stamped
+// when their statement runs, not when the transaction commits, so a write
+// committed just after a cursor was taken can carry an older timestamp; the
+// window covers any card transaction still open at that point.
+const cursorWindow = 10 * time.Second
+
+// Tombstone records a deleted card so incremental clients can drop it
+type Tombstone struct {
+	ID        int64     `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Changes is the response to GET .../cards?since=<cursor>
+type Changes struct {
+	Cards   []Card      `json:"cards"`
+	Deleted []Tombstone `json:"deleted"`
+	Cursor  string      `json:"cursor"` // pass back as ?since= on the next request
+}
+
+// CurrentCursor returns a cursor for "now" less cursorWindow (database
+// clock, in microseconds). Take it before reading so concurrent writes show
+// up in the next feed.
+func CurrentCursor(db *sql.DB) (string, error) {
+	var micros int64
+	if err := db.QueryRow("SELECT CAST(UNIX_TIMESTAMP(NOW(6)) * 1000000 AS UNSIGNED)").Scan(&micros); err != nil {
+		return "", err
+	}
+	micros = max(0, micros-cursorWindow.Microseconds())
+	return strconv.FormatInt(micros, 10), nil
+}
+
+func parseCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	micros, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || micros < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return micros, nil
+}
+
+// GetCardChanges returns cards created or updated, and cards deleted, at or
+// after the cursor. Rows within cursorWindow of a cursor repeat across calls;
+// clients upsert by ID.
+func GetCardChanges(db *sql.DB, boardID int64, since string) (Changes, error) {
+	micros, err := parseCursor(since)
+	if err != nil {
+		return Changes{}, err
+	}
+	// Seconds with a fractional part, for FROM_UNIXTIME; compared against the
+	// bare columns so the (board_id, time) indexes apply
+	seconds := fmt.Sprintf("%d.%06d", micros/1000000, micros%1000000)
+
+	next, err := CurrentCursor(db)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	rows, err := db.Query(
+		"SELECT id, board_id, kind, COALESCE(text, '') AS text, COALESCE(image_url, '') AS image_url, position_x, position_y, width, height, version, created_at, COALESCE(updated_at, created_at) FROM cards WHERE board_id = ? AND updated_at >= FROM_UNIXTIME(?)",
+		boardID, seconds,
+	)
+	if err != nil {
+		return Changes{}, err
+	}
+	defer rows.Close()
+
+	cards := []Card{}
+	for rows.Next() {
+		var c Card
+		if err := rows.Scan(&c.ID, &c.BoardID, &c.Kind, &c.Text, &c.ImageURL, &c.PositionX, &c.PositionY, &c.Width, &c.Height, &c.Version, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return Changes{}, err
+		}
+		cards = append(cards, c)
+	}
+	if err := rows.Err(); err != nil {
+		return Changes{}, err
+	}
+	rows.Close()
+	if err := attachVariants(db, cards); err != nil {
+		return Changes{}, err
+	}
+
+	tombRows, err := db.Query(
+		"SELECT card_id, deleted_at FROM card_tombstones WHERE board_id = ? AND deleted_at >= FROM_UNIXTIME(?)",
+		boardID, seconds,
+	)
+	if err != nil {
+		return Changes{}, err
+	}
+	defer tombRows.Close()
+
+	deleted := []Tombstone{}
+	for tombRows.Next() {
+		var t Tombstone
+		if err := tombRows.Scan(&t.ID, &t.DeletedAt); err != nil {
+			return Changes{}, err
+		}
+		deleted = append(deleted, t)
+	}
+	if err := tombRows.Err(); err != nil {
+		return Changes{}, err
+	}
+
+	return Changes{Cards: cards, Deleted: deleted, Cursor: next}, nil
+}
+
+// WriteCardList serves GET .../cards for a board. With ?since=<cursor> it
+// returns a Changes document; otherwise the full card array, with a cursor
+// for the next incremental fetch in the X-Cards-Cursor header.
+func WriteCardList(w http.ResponseWriter, r *http.Request, db *sql.DB, boardID int64) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Query().Has("since") {
+		changes, err := GetCardChanges(db, boardID, r.URL.Query().Get("since"))
+		if err == ErrInvalidCursor {
+			middleware.JSONError(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			middleware.JSONError(w, "Failed to fetch card changes", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Cards-Cursor", changes.Cursor)
+		json.NewEncoder(w).Encode(changes)
+		return
+	}
+
+	cursor, err := CurrentCursor(db)
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch cards", http.StatusInternalServerError)
+		return
+	}
+	cards, err := GetCardsByBoard(db, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch cards", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Cards-Cursor", cursor)
+	json.NewEncoder(w).Encode(cards)
+}
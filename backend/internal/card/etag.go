@@ -0,0 +1,61 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// ETag formats a card version as an HTTP entity tag
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ExpectedVersion returns the version a PUT was based on, taken from the
+// If-Match header or else the body's "version" field. 0 means the client sent
+// neither (or If-Match: *) and the update is unconditional.
+func ExpectedVersion(r *http.Request, bodyVersion *int64) (int64, error) {
+	if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch != "" {
+		if ifMatch == "*" {
+			return 0, nil
+		}
+		tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		version, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil || version < 1 {
+			return 0, errInvalidIfMatch
+		}
+		return version, nil
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, nil
+	}
+	return 0, nil
+}
+
+// WriteUpdated responds to a successful PUT with the card's new version
+func WriteUpdated(w http.ResponseWriter, db *sql.DB, cardID int64) {
+	resp := map[string]interface{}{"status": "updated"}
+	if c, err := GetCard(db, cardID); err == nil {
+		w.Header().Set("ETag", ETag(c.Version))
+		resp["version"] = c.Version
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// WriteConflict responds 409 with the card as it currently is on the server
+func WriteConflict(w http.ResponseWriter, db *sql.DB, cardID int64) {
+	resp := map[string]interface{}{"error": ErrVersionConflict.Error()}
+	if c, err := GetCard(db, cardID); err == nil {
+		w.Header().Set("ETag", ETag(c.Version))
+		resp["card"] = c
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,77 @@
+package card
+
+import (
+	"log"
+)
+
+// Event types broadcast when a card or link changes
+const (
+	EventCardCreated = "card.created"
+	EventCardUpdated = "card.updated"
+	EventCardDeleted = "card.deleted"
+
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+
+	EventBoardRestored = "board.restored" // clients should refetch the board
+)
+
+type Event struct {
+	Type    string `json:"type"`
+	BoardID int64  `json:"board_id"`
+	CardID  int64  `json:"card_id,omitempty"`
+	Card    *Card  `json:"card,omitempty"` // nil for deletes
+	LinkID  int64  `json:"link_id,omitempty"`
+	Link    *Link  `json:"link,omitempty"` // nil for deletes
+}
+
+// Publisher receives card events after they have been written to the database
+type Publisher interface {
+	Publish(ev Event)
+}
+
+// Publishers sends each event to every publisher in turn
+type Publishers []Publisher
+
+func (ps Publishers) Publish(ev Event) {
+	for _, p := range ps {
+		p.Publish(ev)
+	}
+}
+
+var publisher Publisher
+
+// SetPublisher registers the publisher notified by the card model functions
+func SetPublisher(p Publisher) {
+	publisher = p
+}
+
+// publishCard loads the current card row and publishes it (best-effort)
+func publishCard(db DBTX, eventType string, cardID int64) {
+	if publisher == nil {
+		return
+	}
+	c, err := GetCard(db, cardID)
+	if err != nil {
+		log.Printf("WARN: failed to load card %d for %s event: %v", cardID, eventType, err)
+		return
+	}
+	emit(db, Event{Type: eventType, BoardID: c.BoardID, CardID: c.ID, Card: &c})
+}
+
+func publishDelete(db DBTX, boardID, cardID int64) {
+	if publisher == nil {
+		return
+	}
+	emit(db, Event{Type: EventCardDeleted, BoardID: boardID, CardID: cardID})
+}
+
+// emit publishes now, or queues the event when running inside a *Tx
+func emit(db DBTX, ev Event) {
+	if tx, ok := db.(*Tx); ok {
+		tx.events = append(tx.events, ev)
+		return
+	}
+	publisher.Publish(ev)
+}
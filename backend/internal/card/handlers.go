@@ -1,7 +1,6 @@
 package card
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
@@ -11,9 +10,8 @@ import (
 
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
-
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 type CardHandler struct {
@@ -21,9 +19,8 @@ type CardHandler struct {
 }
 
 type CardOnlyHandler struct {
-	DB       *sql.DB
-	S3Client *s3.Client
-	Bucket   string
+	DB    *sql.DB
+	Store storage.Store // signs image URLs in card history
 }
 
 type createCardReq struct {
@@ -40,15 +37,19 @@ type updateCardReq struct {
 	Text      *string  `json:"text,omitempty"`
 	PositionX *float64 `json:"position_x,omitempty"`
 	PositionY *float64 `json:"position_y,omitempty"`
-	Width     *float64 `json:"width,omitempty"`  // images only
-	Height    *float64 `json:"height,omitempty"` // images only
+	Width     *float64 `json:"width,omitempty"`   // images only
+	Height    *float64 `json:"height,omitempty"`  // images only
+	Version   *int64   `json:"version,omitempty"` // alternative to If-Match
 }
 
 // Routes handled:
 // - POST   /boards/{id}/cards
-// - GET    /boards/{id}/cards
+// - GET    /boards/{id}/cards[?since=<cursor>]
+// - GET    /cards/{id}
 // - PUT    /cards/{id}
 // - DELETE /cards/{id}
+// - GET    /cards/{id}/history
+// - POST   /cards/{id}/history/{revID}/revert
 func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	userID := user.GetUserID(r)
 	if userID == 0 {
@@ -104,7 +105,9 @@ func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				if strings.TrimSpace(body.Text) == "" {
 					body.Text = ""
 				}
-				id, err := CreateCard(h.DB, boardID, body.Text, body.PositionX, body.PositionY)
+				id, err := Revise(h.DB, UserActor(userID), RevisionCreate, 0, func(tx *Tx) (int64, error) {
+					return CreateCard(tx, boardID, body.Text, body.PositionX, body.PositionY)
+				})
 				if err != nil {
 					middleware.JSONError(w, "Failed to create card", http.StatusInternalServerError)
 					return
@@ -116,6 +119,7 @@ func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					"text":       body.Text,
 					"position_x": body.PositionX,
 					"position_y": body.PositionY,
+					"version":    1,
 				})
 			// Create Image Card
 			case "image":
@@ -123,7 +127,13 @@ func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					middleware.JSONError(w, "image_url is required for kind=image", http.StatusBadRequest)
 					return
 				}
-				id, err := CreateImageCard(h.DB, boardID, body.ImageURL, body.PositionX, body.PositionY, body.Width, body.Height)
+				id, err := Revise(h.DB, UserActor(userID), RevisionCreate, 0, func(tx *Tx) (int64, error) {
+					return CreateImageCard(tx, boardID, body.ImageURL, body.PositionX, body.PositionY, body.Width, body.Height)
+				})
+				if err == ErrImageNotConfirmed {
+					middleware.JSONError(w, err.Error(), http.StatusUnprocessableEntity)
+					return
+				}
 				if err != nil {
 					log.Print(err)
 					middleware.JSONError(w, "Failed to create image card", http.StatusInternalServerError)
@@ -138,6 +148,7 @@ func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					"position_y": body.PositionY,
 					"width":      body.Width,
 					"height":     body.Height,
+					"version":    1,
 				})
 
 			default:
@@ -149,12 +160,8 @@ func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				middleware.JSONError(w, "Forbidden", http.StatusForbidden)
 				return
 			}
-			cards, err := GetCardsByBoard(h.DB, boardID)
-			if err != nil {
-				middleware.JSONError(w, "Failed to fetch cards", http.StatusInternalServerError)
-				return
-			}
-			json.NewEncoder(w).Encode(cards)
+			// Full list, or only what changed with ?since=<cursor>
+			WriteCardList(w, r, h.DB, boardID)
 
 		default:
 			middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -184,6 +191,12 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// --- History routes: /cards/{id}/history[/{revID}/revert] ---
+		if len(parts) > 3 && parts[3] == "history" {
+			serveHistory(w, r, h.DB, h.Store, userID, cardID, parts)
+			return
+		}
+
 		// Find the board ID for this card so we can check permissions
 		var boardID int64
 		err = h.DB.QueryRow("SELECT board_id FROM cards WHERE id = ?", cardID).Scan(&boardID)
@@ -203,6 +216,20 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 		switch r.Method {
+		case http.MethodGet:
+			if perm == board.PermissionNone {
+				middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			c, err := GetCard(h.DB, cardID)
+			if err != nil {
+				middleware.JSONError(w, "Failed to fetch card", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("ETag", ETag(c.Version))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(c)
+
 		case http.MethodPut:
 			if perm != board.PermissionOwner && perm != board.PermissionEdit {
 				middleware.JSONError(w, "Forbidden", http.StatusForbidden)
@@ -231,6 +258,11 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
 				return
 			}
+			version, err := ExpectedVersion(r, body.Version)
+			if err != nil {
+				middleware.JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			// Default current positions if not provided
 			var curX, curY float64
@@ -263,7 +295,13 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					txt = *body.Text
 				}
 
-				affected, err := UpdateCard(h.DB, cardID, txt, x, y)
+				affected, err := Revise(h.DB, UserActor(userID), RevisionUpdate, cardID, func(tx *Tx) (int64, error) {
+					return UpdateCard(tx, cardID, txt, x, y, version)
+				})
+				if err == ErrVersionConflict {
+					WriteConflict(w, h.DB, cardID)
+					return
+				}
 				if err != nil {
 					middleware.JSONError(w, "Failed to update card", http.StatusInternalServerError)
 					return
@@ -272,7 +310,7 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					json.NewEncoder(w).Encode(map[string]string{"status": "no rows affected"})
 					return
 				}
-				json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+				WriteUpdated(w, h.DB, cardID)
 
 			case "image":
 				// Default current pos/size if omitted
@@ -306,7 +344,13 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					hPtr = &val
 				}
 
-				affected, err := UpdateImageCard(h.DB, cardID, x, y, wPtr, hPtr)
+				affected, err := Revise(h.DB, UserActor(userID), RevisionUpdate, cardID, func(tx *Tx) (int64, error) {
+					return UpdateImageCard(tx, cardID, x, y, wPtr, hPtr, version)
+				})
+				if err == ErrVersionConflict {
+					WriteConflict(w, h.DB, cardID)
+					return
+				}
 				if err != nil {
 					middleware.JSONError(w, "Failed to update image card", http.StatusInternalServerError)
 					return
@@ -315,7 +359,7 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					json.NewEncoder(w).Encode(map[string]string{"status": "no rows affected"})
 					return
 				}
-				json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+				WriteUpdated(w, h.DB, cardID)
 
 			default:
 				middleware.JSONError(w, "invalid card kind", http.StatusBadRequest)
@@ -327,34 +371,10 @@ func (h *CardOnlyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			// 1) Look up info for possible S3 cleanup (kind + image_url)
-			var kind, imageURL string
-			if err := h.DB.QueryRow("SELECT kind, COALESCE(image_url, ''), board_id FROM cards WHERE id = ?", cardID).
-				Scan(&kind, &imageURL, &boardID); err != nil {
-				if err == sql.ErrNoRows {
-					middleware.JSONError(w, "Card not found", http.StatusNotFound)
-					return
-				}
-				middleware.JSONError(w, "Failed to fetch card", http.StatusInternalServerError)
-				return
-			}
-
-			// 2) If image card, attempt to delete S3 object (best-effort)
-			if kind == "image" && imageURL != "" && h.S3Client != nil && h.Bucket != "" {
-				if idx := strings.LastIndex(imageURL, "images/"); idx != -1 {
-					s3Key := imageURL[idx:] // e.g. "images/<boardID>/<uuid>.jpg"
-					_, err := h.S3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-						Bucket: &h.Bucket,
-						Key:    &s3Key,
-					})
-					if err != nil {
-						log.Printf("WARN: failed to delete S3 object %s: %v", s3Key, err)
-					}
-				}
-			}
-
-			// 3) Delete the DB row
-			affected, err := DeleteCard(h.DB, cardID)
+			// Stored images stay behind for undo and restores; gc collects them
+			affected, err := Revise(h.DB, UserActor(userID), RevisionDelete, cardID, func(tx *Tx) (int64, error) {
+				return DeleteCard(tx, cardID)
+			})
 			if err != nil {
 				middleware.JSONError(w, "Failed to delete card", http.StatusInternalServerError)
 				return
@@ -0,0 +1,125 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// serveHistory handles:
+// - GET  /cards/{id}/history[?before=<revID>&limit=<n>]  (any access)
+// - POST /cards/{id}/history/{revID}/revert              (owner or edit; honors If-Match)
+//
+// History stays readable after the card is deleted. Image keys in the
+// listed states are signed with store.
+func serveHistory(w http.ResponseWriter, r *http.Request, db *sql.DB, store storage.Store, userID, cardID int64, parts []string) {
+	boardID, err := CardBoardID(db, cardID)
+	if err == sql.ErrNoRows {
+		middleware.JSONError(w, "Card not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch card", http.StatusInternalServerError)
+		return
+	}
+
+	perm, err := board.GetUserPermission(db, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// --- List: /cards/{id}/history ---
+	if len(parts) == 4 {
+		if r.Method != http.MethodGet {
+			middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		var before int64
+		if v := q.Get("before"); v != "" {
+			before, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || before < 0 {
+				middleware.JSONError(w, "Invalid before", http.StatusBadRequest)
+				return
+			}
+		}
+		limit := defaultHistoryLimit
+		if v := q.Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit <= 0 {
+				middleware.JSONError(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			if limit > maxHistoryLimit {
+				limit = maxHistoryLimit
+			}
+		}
+
+		revisions, err := GetCardHistory(db, cardID, before, limit)
+		if err != nil {
+			middleware.JSONError(w, "Failed to fetch history", http.StatusInternalServerError)
+			return
+		}
+		SignRevisions(r.Context(), store, revisions)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(revisions)
+		return
+	}
+
+	// --- Revert: /cards/{id}/history/{revID}/revert ---
+	if len(parts) != 6 || parts[5] != "revert" {
+		middleware.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if perm != board.PermissionOwner && perm != board.PermissionEdit {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	revisionID, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+	version, err := ExpectedVersion(r, nil)
+	if err != nil {
+		middleware.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = RevertCard(db, UserActor(userID), cardID, revisionID, version)
+	if err == ErrRevisionNotFound {
+		middleware.JSONError(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	if err == ErrVersionConflict {
+		WriteConflict(w, db, cardID)
+		return
+	}
+	if err != nil {
+		log.Printf("revert of card %d to revision %d failed: %v", cardID, revisionID, err)
+		middleware.JSONError(w, "Failed to revert card", http.StatusInternalServerError)
+		return
+	}
+	WriteUpdated(w, db, cardID)
+}
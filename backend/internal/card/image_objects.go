@@ -0,0 +1,131 @@
+package card
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// Images are stored once per content, under images/sha256/<hash><ext>, and
+// image_objects counts the cards that use each one. Nothing here deletes
+// them: cards that let go of an image can come back through undo, revert or a
+// snapshot restore, so gc.Sweep removes an image once no card, recent
+// revision, recent automatic snapshot or manual snapshot refers to it.
+
+// contentKey is the storage key for a cleaned original
+func contentKey(res *imageproc.Result) string {
+	sum := sha256.Sum256(res.Original.Data)
+	return "images/sha256/" + hex.EncodeToString(sum[:]) + res.Original.Ext
+}
+
+// putObject stores a processed image under its content key unless an
+// identical one is already there, and returns the key
+func putObject(ctx context.Context, db *sql.DB, store storage.Store, res *imageproc.Result) (string, error) {
+	key := contentKey(res)
+	variants, err := variantsJSON(key, res)
+	if err != nil {
+		return "", err
+	}
+	// Record the object before writing it, so a concurrent gc sweep either
+	// finishes first or sees the fresh upload and leaves it alone
+	_, err = db.Exec(
+		`INSERT INTO image_objects (storage_key, content_type, size, width, height, variants)
+         VALUES (?, ?, ?, ?, ?, ?)
+         ON DUPLICATE KEY UPDATE last_uploaded_at = CURRENT_TIMESTAMP`,
+		key, res.Original.ContentType, res.Size(), res.Original.Width, res.Original.Height, variants,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = store.Stat(ctx, key)
+	if err == nil {
+		return key, nil
+	}
+	if err != storage.ErrNotFound {
+		return "", err
+	}
+	if err := storeProcessed(ctx, store, key, res); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// storedImageKey is the key behind imageURL when it names an image in the
+// store, as a bare key or a URL into the store. External URLs, even ones
+// with "images/" somewhere in their path, are not ours and report false.
+func storedImageKey(imageURL string) (string, bool) {
+	key := storage.Normalize(imageURL)
+	if !storage.IsKey(key) || !strings.HasPrefix(key, "images/") {
+		return "", false
+	}
+	return key, true
+}
+
+// retainImage counts a new card on boardID using imageURL. Only images
+// uploaded to this board, or already on one of its cards, may be used:
+// keys seen on another board (say through a share link since revoked) are
+// refused, as are keys with no stored object behind them (presigned uploads
+// that were never confirmed, images already deleted). External URLs are left
+// alone.
+func retainImage(db DBTX, boardID int64, imageURL string) error {
+	key, ok := storedImageKey(imageURL)
+	if !ok {
+		return nil
+	}
+	var onBoard bool
+	err := db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM image_uploads WHERE board_id = ? AND object_key = ? AND status = ?)
+             OR EXISTS (SELECT 1 FROM cards WHERE board_id = ? AND image_url = ?)`,
+		boardID, key, UploadConfirmed, boardID, key,
+	).Scan(&onBoard)
+	if err != nil {
+		return err
+	}
+	if !onBoard {
+		return ErrImageNotConfirmed
+	}
+	res, err := db.Exec("UPDATE image_objects SET ref_count = ref_count + 1 WHERE storage_key = ?", key)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrImageNotConfirmed
+	}
+	return nil
+}
+
+// swapImageRef moves one card reference from oldURL to newURL. Unlike
+// retainImage it never fails for an unknown key: restores bring back
+// whatever the card held.
+func swapImageRef(db DBTX, oldURL, newURL string) error {
+	if oldURL == newURL {
+		return nil
+	}
+	if key, ok := storedImageKey(newURL); ok {
+		if _, err := db.Exec("UPDATE image_objects SET ref_count = ref_count + 1 WHERE storage_key = ?", key); err != nil {
+			return err
+		}
+	}
+	return releaseImage(db, oldURL)
+}
+
+// releaseImage drops one card reference to imageURL. The object stays in
+// storage for gc.Sweep to collect.
+func releaseImage(db DBTX, imageURL string) error {
+	key, ok := storedImageKey(imageURL)
+	if !ok {
+		return nil
+	}
+	_, err := db.Exec("UPDATE image_objects SET ref_count = GREATEST(ref_count - 1, 0) WHERE storage_key = ?", key)
+	return err
+}
@@ -0,0 +1,50 @@
+package card
+
+import (
+	"testing"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+func TestStoredImageKey(t *testing.T) {
+	storage.SetSigner(&storage.LocalStore{BaseURL: "http://localhost:8080", Secret: []byte("test")})
+	defer storage.SetSigner(nil)
+
+	tests := []struct {
+		url  string
+		key  string
+		want bool
+	}{
+		{"images/sha256/abc.png", "images/sha256/abc.png", true},
+		{"images/12/upload.jpg", "images/12/upload.jpg", true},
+		{"http://localhost:8080/files/images/sha256/abc.png", "images/sha256/abc.png", true},
+		{"http://localhost:8080/files/images/sha256/abc.png?expires=1&sig=x", "images/sha256/abc.png", true},
+		{"https://cdn.example.com/images/logo.png", "", false},
+		{"https://example.com/a/b/images/sha256/abc.png?x=1", "", false},
+		{"http://localhost:8080/files/thumbnails/1.png", "", false},
+		{"thumbnails/1.png", "", false},
+		{"images/../secrets.txt", "", false},
+		{"/images/abc.png", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		key, ok := storedImageKey(tt.url)
+		if ok != tt.want || key != tt.key {
+			t.Errorf("storedImageKey(%q) = %q, %v; want %q, %v", tt.url, key, ok, tt.key, tt.want)
+		}
+	}
+}
+
+// External image URLs need no upload on the board, so retainImage never
+// looks them up
+func TestRetainImageExternal(t *testing.T) {
+	for _, url := range []string{
+		"https://cdn.example.com/images/logo.png",
+		"https://example.com/photo.jpg",
+		"http://example.com/static/images/sha256/abc.png?w=200",
+	} {
+		if err := retainImage(nil, 1, url); err != nil {
+			t.Errorf("retainImage(%q) = %v, want nil", url, err)
+		}
+	}
+}
@@ -1,40 +1,33 @@
 package card
 
 import (
-	"context"
 	"database/sql"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
-	"github.com/LoganTackett1/brainstorming-backend/internal/awsclient"
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
-	"github.com/google/uuid"
-
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 type BoardImageUploadHandler struct {
-	DB     *sql.DB
-	S3     *s3.Client
-	Bucket string
+	DB    *sql.DB
+	Store storage.Store
 }
 
-func NewBoardImageUploadHandler(db *sql.DB) *BoardImageUploadHandler {
+func NewBoardImageUploadHandler(db *sql.DB, store storage.Store) *BoardImageUploadHandler {
 	return &BoardImageUploadHandler{
-		DB:     db,
-		S3:     awsclient.NewS3Client(),
-		Bucket: os.Getenv("S3_BUCKET"),
+		DB:    db,
+		Store: store,
 	}
 }
 
-// POST /boards/{id}/images   (multipart form: file=<file>)
+// Routes handled:
+// - POST /boards/{id}/images           (multipart form: file=<file>)
+// - POST /boards/{id}/images/presign   direct upload to storage, see ServePresign
+// - POST /boards/{id}/images/confirm   finish a direct upload, see ServeConfirm
 // perms: owner or edit
 func (h *BoardImageUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	userID := user.GetUserID(r)
@@ -70,37 +63,18 @@ func (h *BoardImageUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// 10 MB max
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		middleware.JSONError(w, "Invalid form data", http.StatusBadRequest)
-		return
-	}
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		middleware.JSONError(w, "Missing file", http.StatusBadRequest)
+	// /boards/{id}/images/{presign|confirm}
+	if len(parts) == 5 {
+		switch parts[4] {
+		case "presign":
+			ServePresign(w, r, h.DB, h.Store, boardID, UserActor(userID))
+		case "confirm":
+			ServeConfirm(w, r, h.DB, h.Store, boardID)
+		default:
+			middleware.JSONError(w, "Not found", http.StatusNotFound)
+		}
 		return
 	}
-	defer file.Close()
-
-	// S3 key: images/{boardID}/{uuid}.{ext}
-	ext := strings.ToLower(filepath.Ext(handler.Filename))
-	if ext == "" {
-		ext = ".bin"
-	}
-	key := fmt.Sprintf("images/%d/%s%s", boardID, uuid.New().String(), ext)
-
-	_, err = h.S3.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket:      &h.Bucket,
-		Key:         &key,
-		Body:        file,
-	})
-	if err != nil {
-		middleware.JSONError(w, "Failed to upload to S3", http.StatusInternalServerError)
-		return
-	}
-
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", h.Bucket, os.Getenv("AWS_REGION"), key)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"url": url})
+	ServeImageUpload(w, r, h.DB, h.Store, boardID, UserActor(userID))
 }
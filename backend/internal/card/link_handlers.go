@@ -0,0 +1,204 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+type LinkHandler struct {
+	DB *sql.DB
+}
+
+type createLinkReq struct {
+	SourceCardID int64  `json:"source_card_id"`
+	TargetCardID int64  `json:"target_card_id"`
+	Label        string `json:"label"`
+	Style        string `json:"style"`              // default: "solid"
+	Directed     *bool  `json:"directed,omitempty"` // default: true
+}
+
+type updateLinkReq struct {
+	Label    *string `json:"label,omitempty"`
+	Style    *string `json:"style,omitempty"`
+	Directed *bool   `json:"directed,omitempty"`
+}
+
+// Routes handled:
+// - GET    /boards/{id}/links
+// - POST   /boards/{id}/links
+// - PUT    /boards/{id}/links/{linkID}
+// - DELETE /boards/{id}/links/{linkID}
+func (h *LinkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 || parts[1] != "boards" || parts[3] != "links" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+	linkID, ok := ParseLinkID(w, parts)
+	if !ok {
+		return
+	}
+
+	// Same rules as CardHandler: any access reads, owner/edit writes
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	ServeLinks(w, r, h.DB, boardID, linkID, perm == board.PermissionOwner || perm == board.PermissionEdit)
+}
+
+// ParseLinkID reads the optional {linkID} segment after .../links (parts[4]);
+// 0 means the collection route. Writes a 400 and returns false when malformed.
+func ParseLinkID(w http.ResponseWriter, parts []string) (int64, bool) {
+	if len(parts) < 5 || parts[4] == "" {
+		return 0, true
+	}
+	linkID, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil || len(parts) > 5 {
+		middleware.JSONError(w, "Invalid link ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return linkID, true
+}
+
+// ServeLinks handles link CRUD for a board once the caller has checked read
+// access. linkID is 0 for the collection routes. Shared with share links.
+func ServeLinks(w http.ResponseWriter, r *http.Request, db *sql.DB, boardID, linkID int64, canEdit bool) {
+	if r.Method != http.MethodGet && !canEdit {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// --- Collection: .../links ---
+	if linkID == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			links, err := GetLinksByBoard(db, boardID)
+			if err != nil {
+				middleware.JSONError(w, "Failed to fetch links", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(links)
+
+		case http.MethodPost:
+			var body createLinkReq
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			directed := true
+			if body.Directed != nil {
+				directed = *body.Directed
+			}
+
+			id, err := CreateLink(db, boardID, body.SourceCardID, body.TargetCardID, body.Label, strings.ToLower(strings.TrimSpace(body.Style)), directed)
+			if err == ErrLinkEndpoint || err == ErrLinkStyle || err == ErrLinkLabel {
+				middleware.JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Print(err)
+				middleware.JSONError(w, "Failed to create link", http.StatusInternalServerError)
+				return
+			}
+			link, err := GetLink(db, id)
+			if err != nil {
+				middleware.JSONError(w, "Failed to fetch link", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(link)
+
+		default:
+			middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	// --- Single link: .../links/{linkID} ---
+	existing, err := GetLink(db, linkID)
+	if err == sql.ErrNoRows || (err == nil && existing.BoardID != boardID) {
+		middleware.JSONError(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch link", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var body updateLinkReq
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Omitted fields keep their current values
+		label, style, directed := existing.Label, existing.Style, existing.Directed
+		if body.Label != nil {
+			label = *body.Label
+		}
+		if body.Style != nil {
+			style = strings.ToLower(strings.TrimSpace(*body.Style))
+		}
+		if body.Directed != nil {
+			directed = *body.Directed
+		}
+
+		if _, err := UpdateLink(db, linkID, label, style, directed); err != nil {
+			if err == ErrLinkStyle || err == ErrLinkLabel {
+				middleware.JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			middleware.JSONError(w, "Failed to update link", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+
+	case http.MethodDelete:
+		affected, err := DeleteLink(db, linkID)
+		if err != nil {
+			middleware.JSONError(w, "Failed to delete link", http.StatusInternalServerError)
+			return
+		}
+		if affected == 0 {
+			middleware.JSONError(w, "Link not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+
+	default:
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
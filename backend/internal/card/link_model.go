@@ -0,0 +1,202 @@
+package card
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Link styles
+const (
+	LinkStyleSolid  = "solid"
+	LinkStyleDashed = "dashed"
+	LinkStyleDotted = "dotted"
+)
+
+var (
+	ErrLinkEndpoint = errors.New("source and target must be different cards on this board")
+	ErrLinkStyle    = errors.New("style must be 'solid', 'dashed' or 'dotted'")
+	ErrLinkLabel    = errors.New("label must be at most 255 characters")
+)
+
+const maxLinkLabel = 255
+
+// Link is a connector drawn between two cards on the same board. Deleting
+// either card removes the link (ON DELETE CASCADE).
+type Link struct {
+	ID           int64     `json:"id"`
+	BoardID      int64     `json:"board_id"`
+	SourceCardID int64     `json:"source_card_id"`
+	TargetCardID int64     `json:"target_card_id"`
+	Label        string    `json:"label"`
+	Style        string    `json:"style"`    // "solid" | "dashed" | "dotted"
+	Directed     bool      `json:"directed"` // arrow from source to target
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func ValidLinkStyle(style string) bool {
+	switch style {
+	case LinkStyleSolid, LinkStyleDashed, LinkStyleDotted:
+		return true
+	}
+	return false
+}
+
+// CreateLink connects two cards; both must belong to boardID
+func CreateLink(db DBTX, boardID, sourceID, targetID int64, label, style string, directed bool) (int64, error) {
+	if sourceID == targetID {
+		return 0, ErrLinkEndpoint
+	}
+	if style == "" {
+		style = LinkStyleSolid
+	}
+	if !ValidLinkStyle(style) {
+		return 0, ErrLinkStyle
+	}
+	if len([]rune(label)) > maxLinkLabel {
+		return 0, ErrLinkLabel
+	}
+
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM cards WHERE board_id = ? AND id IN (?, ?)",
+		boardID, sourceID, targetID,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count != 2 {
+		return 0, ErrLinkEndpoint
+	}
+
+	res, err := db.Exec(
+		"INSERT INTO card_links (board_id, source_card_id, target_card_id, label, style, directed) VALUES (?, ?, ?, ?, ?, ?)",
+		boardID, sourceID, targetID, label, style, directed,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	publishLink(db, EventLinkCreated, id)
+	return id, nil
+}
+
+func GetLink(db DBTX, linkID int64) (Link, error) {
+	var l Link
+	err := db.QueryRow(
+		"SELECT id, board_id, source_card_id, target_card_id, label, style, directed, created_at, COALESCE(updated_at, created_at) FROM card_links WHERE id = ?",
+		linkID,
+	).Scan(&l.ID, &l.BoardID, &l.SourceCardID, &l.TargetCardID, &l.Label, &l.Style, &l.Directed, &l.CreatedAt, &l.UpdatedAt)
+	return l, err
+}
+
+func GetLinksByBoard(db DBTX, boardID int64) ([]Link, error) {
+	rows, err := db.Query(
+		"SELECT id, board_id, source_card_id, target_card_id, label, style, directed, created_at, COALESCE(updated_at, created_at) FROM card_links WHERE board_id = ?",
+		boardID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []Link
+	for rows.Next() {
+		var l Link
+		if err := rows.Scan(&l.ID, &l.BoardID, &l.SourceCardID, &l.TargetCardID, &l.Label, &l.Style, &l.Directed, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+
+	if links == nil {
+		links = []Link{}
+	}
+
+	return links, nil
+}
+
+func UpdateLink(db DBTX, linkID int64, label, style string, directed bool) (int64, error) {
+	if !ValidLinkStyle(style) {
+		return 0, ErrLinkStyle
+	}
+	if len([]rune(label)) > maxLinkLabel {
+		return 0, ErrLinkLabel
+	}
+	res, err := db.Exec(
+		"UPDATE card_links SET label = ?, style = ?, directed = ? WHERE id = ?",
+		label, style, directed, linkID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected > 0 {
+		publishLink(db, EventLinkUpdated, linkID)
+	}
+	return affected, nil
+}
+
+func DeleteLink(db DBTX, linkID int64) (int64, error) {
+	var boardID int64
+	err := db.QueryRow("SELECT board_id FROM card_links WHERE id = ?", linkID).Scan(&boardID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := db.Exec("DELETE FROM card_links WHERE id = ?", linkID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected > 0 && publisher != nil {
+		emit(db, Event{Type: EventLinkDeleted, BoardID: boardID, LinkID: linkID})
+	}
+	return affected, nil
+}
+
+// cardLinkIDs lists the links to or from a card
+func cardLinkIDs(db DBTX, cardID int64) ([]int64, error) {
+	rows, err := db.Query("SELECT id FROM card_links WHERE source_card_id = ? OR target_card_id = ?", cardID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// publishLink loads the current link row and publishes it (best-effort)
+func publishLink(db DBTX, eventType string, linkID int64) {
+	if publisher == nil {
+		return
+	}
+	l, err := GetLink(db, linkID)
+	if err != nil {
+		log.Printf("WARN: failed to load link %d for %s event: %v", linkID, eventType, err)
+		return
+	}
+	emit(db, Event{Type: eventType, BoardID: l.BoardID, LinkID: l.ID, Link: &l})
+}
@@ -2,23 +2,47 @@ package card
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
 )
 
+// ErrVersionConflict is returned when an update's expected version is stale
+var ErrVersionConflict = errors.New("card was modified by someone else")
+
 type Card struct {
 	ID        int64     `json:"id"`
 	BoardID   int64     `json:"board_id"`
 	Kind 	  string 	`json:"kind"`
 	Text      string    `json:"text,omitempty"`
 	ImageURL  string    `json:"image_url,omitempty"`
+	Variants  map[string]string `json:"image_variants,omitempty"` // smaller renditions by size, e.g. "256"
 	PositionX float64   `json:"position_x"`
 	PositionY float64   `json:"position_y"`
     Width     *float64  `json:"width,omitempty"`
     Height    *float64  `json:"height,omitempty"`
+	Version   int64     `json:"version"` // bumped on every update; also sent as the ETag
 	CreatedAt time.Time `json:"created_at"`
     UpdatedAt time.Time `json:"updated_at"`
 }
 
+// MarshalJSON hands clients short-lived signed URLs in place of the stored
+// image and variant keys
+func (c Card) MarshalJSON() ([]byte, error) {
+	type plain Card
+	p := plain(c)
+	p.ImageURL = storage.Sign(c.ImageURL)
+	if c.Variants != nil {
+		p.Variants = make(map[string]string, len(c.Variants))
+		for size, key := range c.Variants {
+			p.Variants[size] = storage.Sign(key)
+		}
+	}
+	return json.Marshal(p)
+}
+
 // VerifyBoardOwnership checks if a board belongs to the user
 func VerifyBoardOwnership(db *sql.DB, boardID, ownerID int64) (bool, error) {
 	var count int
@@ -29,7 +53,7 @@ func VerifyBoardOwnership(db *sql.DB, boardID, ownerID int64) (bool, error) {
 	return count == 1, nil
 }
 
-func CreateCard(db *sql.DB, boardID int64, text string, x, y float64) (int64, error) {
+func CreateCard(db DBTX, boardID int64, text string, x, y float64) (int64, error) {
 	res, err := db.Exec(
 		"INSERT INTO cards (board_id, text, position_x, position_y) VALUES (?, ?, ?, ?)",
 		boardID, text, x, y,
@@ -37,10 +61,21 @@ func CreateCard(db *sql.DB, boardID int64, text string, x, y float64) (int64, er
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	publishCard(db, EventCardCreated, id)
+	return id, nil
 }
 
-func CreateImageCard(db *sql.DB, boardID int64, imageURL string, x, y float64, width, height *float64) (int64, error) {
+func CreateImageCard(db DBTX, boardID int64, imageURL string, x, y float64, width, height *float64) (int64, error) {
+    // Clients send back the signed URL they were given; keep only the key
+    imageURL = storage.Normalize(imageURL)
+    if err := retainImage(db, boardID, imageURL); err != nil {
+        return 0, err
+    }
+
     // Convert pointer floats to driver-friendly values
     var w interface{} = nil
     var h interface{} = nil
@@ -58,12 +93,32 @@ func CreateImageCard(db *sql.DB, boardID int64, imageURL string, x, y float64, w
     if err != nil {
         return 0, err
     }
-    return res.LastInsertId()
+    id, err := res.LastInsertId()
+    if err != nil {
+        return 0, err
+    }
+    publishCard(db, EventCardCreated, id)
+    return id, nil
 }
 
-func GetCardsByBoard(db *sql.DB, boardID int64) ([]Card, error) {
+// GetCard fetches a single card by ID
+func GetCard(db DBTX, cardID int64) (Card, error) {
+	var c Card
+	err := db.QueryRow(
+		"SELECT id, board_id, kind, COALESCE(text, '') AS text, COALESCE(image_url, '') AS image_url, position_x, position_y, width, height, version, created_at, COALESCE(updated_at, created_at) FROM cards WHERE id = ?",
+		cardID,
+	).Scan(&c.ID, &c.BoardID, &c.Kind, &c.Text, &c.ImageURL, &c.PositionX, &c.PositionY, &c.Width, &c.Height, &c.Version, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return c, err
+	}
+	cards := []Card{c}
+	err = attachVariants(db, cards)
+	return cards[0], err
+}
+
+func GetCardsByBoard(db DBTX, boardID int64) ([]Card, error) {
 	rows, err := db.Query(
-		"SELECT id, board_id, kind, COALESCE(text, '') AS text, COALESCE(image_url, '') AS image_url, position_x, position_y, width, height, created_at, COALESCE(updated_at, created_at) FROM cards WHERE board_id = ?",
+		"SELECT id, board_id, kind, COALESCE(text, '') AS text, COALESCE(image_url, '') AS image_url, position_x, position_y, width, height, version, created_at, COALESCE(updated_at, created_at) FROM cards WHERE board_id = ?",
 		boardID,
 	)
 	if err != nil {
@@ -74,31 +129,47 @@ func GetCardsByBoard(db *sql.DB, boardID int64) ([]Card, error) {
 	var cards []Card
 	for rows.Next() {
 		var c Card
-		if err := rows.Scan(&c.ID, &c.BoardID, &c.Kind, &c.Text, &c.ImageURL, &c.PositionX, &c.PositionY, &c.Width, &c.Height, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.BoardID, &c.Kind, &c.Text, &c.ImageURL, &c.PositionX, &c.PositionY, &c.Width, &c.Height, &c.Version, &c.CreatedAt, &c.UpdatedAt); err != nil {
 			return nil, err
 		}
 		cards = append(cards, c)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
 	if cards == nil {
 		cards = []Card{}
 	}
 
-	return cards, nil
+	return cards, attachVariants(db, cards)
 }
 
-func UpdateCard(db *sql.DB, cardID int64, text string, x, y float64) (int64, error) {
+// UpdateCard updates a text card. When version is non-zero the update only
+// applies if the card is still at that version, otherwise ErrVersionConflict.
+func UpdateCard(db DBTX, cardID int64, text string, x, y float64, version int64) (int64, error) {
 	res, err := db.Exec(
-		"UPDATE cards SET text = ?, position_x = ?, position_y = ? WHERE id = ?",
-		text, x, y, cardID,
+		"UPDATE cards SET text = ?, position_x = ?, position_y = ?, version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)",
+		text, x, y, cardID, version, version,
 	)
 	if err != nil {
 		return 0, err
 	}
-	return res.RowsAffected()
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, checkConflict(db, cardID, version)
+	}
+	publishCard(db, EventCardUpdated, cardID)
+	return affected, nil
 }
 
-func UpdateImageCard(db *sql.DB, cardID int64, x, y float64, width, height *float64) (int64, error) {
+// UpdateImageCard updates an image card's position and size, with the same
+// version semantics as UpdateCard.
+func UpdateImageCard(db DBTX, cardID int64, x, y float64, width, height *float64, version int64) (int64, error) {
     var w interface{} = nil
     var h interface{} = nil
     if width != nil {
@@ -108,19 +179,135 @@ func UpdateImageCard(db *sql.DB, cardID int64, x, y float64, width, height *floa
         h = *height
     }
     res, err := db.Exec(
-        "UPDATE cards SET position_x = ?, position_y = ?, width = ?, height = ? WHERE id = ?",
-        x, y, w, h, cardID,
+        "UPDATE cards SET position_x = ?, position_y = ?, width = ?, height = ?, version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)",
+        x, y, w, h, cardID, version, version,
     )
     if err != nil {
         return 0, err
     }
-    return res.RowsAffected()
+    affected, err := res.RowsAffected()
+    if err != nil {
+        return 0, err
+    }
+    if affected == 0 {
+        return 0, checkConflict(db, cardID, version)
+    }
+    publishCard(db, EventCardUpdated, cardID)
+    return affected, nil
+}
+
+// checkConflict tells a missing card (nil) apart from a stale version
+func checkConflict(db DBTX, cardID, version int64) error {
+	if version == 0 {
+		return nil
+	}
+	var current int64
+	err := db.QueryRow("SELECT version FROM cards WHERE id = ?", cardID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrVersionConflict
 }
 
-func DeleteCard(db *sql.DB, cardID int64) (int64, error) {
-	res, err := db.Exec("DELETE FROM cards WHERE id = ?", cardID)
+func DeleteCard(db DBTX, cardID int64) (int64, error) {
+	// Look up the board first so subscribers know where the card lived
+	var boardID int64
+	var imageURL string
+	err := db.QueryRow("SELECT board_id, COALESCE(image_url, '') FROM cards WHERE id = ?", cardID).Scan(&boardID, &imageURL)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Record a tombstone alongside the delete so the change feed can report it.
+	// The card's links go with it (ON DELETE CASCADE); note them for events.
+	var affected int64
+	var linkIDs []int64
+	err = withTx(db, func(tx DBTX) error {
+		ids, err := cardLinkIDs(tx, cardID)
+		if err != nil {
+			return err
+		}
+		linkIDs = ids
+		res, err := tx.Exec("DELETE FROM cards WHERE id = ?", cardID)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		if err != nil || affected == 0 {
+			return err
+		}
+		if err := releaseImage(tx, imageURL); err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO card_tombstones (card_id, board_id) VALUES (?, ?) ON DUPLICATE KEY UPDATE board_id = VALUES(board_id), deleted_at = CURRENT_TIMESTAMP(6)",
+			cardID, boardID,
+		)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
-	return res.RowsAffected()
+
+	if affected > 0 && publisher != nil {
+		for _, id := range linkIDs {
+			emit(db, Event{Type: EventLinkDeleted, BoardID: boardID, LinkID: id})
+		}
+		publishDelete(db, boardID, cardID)
+	}
+	return affected, nil
+}
+
+// RestoreCard writes c back under its original ID, re-creating it if it was
+// deleted since. The version is bumped so clients holding an older copy get
+// a conflict instead of overwriting the restored card.
+func RestoreCard(db DBTX, c Card) error {
+	var owner int64
+	var oldImageURL string
+	err := db.QueryRow("SELECT board_id, COALESCE(image_url, '') FROM cards WHERE id = ?", c.ID).Scan(&owner, &oldImageURL)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && owner != c.BoardID {
+		return errors.New("card id belongs to another board")
+	}
+
+	var w, h, imageURL interface{}
+	if c.Width != nil {
+		w = *c.Width
+	}
+	if c.Height != nil {
+		h = *c.Height
+	}
+	key := storage.Normalize(c.ImageURL) // older snapshots hold full URLs
+	if key != "" {
+		imageURL = key
+	}
+	if err := swapImageRef(db, oldImageURL, key); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO cards (id, board_id, kind, text, image_url, position_x, position_y, width, height, version, created_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+         ON DUPLICATE KEY UPDATE kind = VALUES(kind), text = VALUES(text), image_url = VALUES(image_url),
+             position_x = VALUES(position_x), position_y = VALUES(position_y),
+             width = VALUES(width), height = VALUES(height), version = cards.version + 1`,
+		c.ID, c.BoardID, c.Kind, c.Text, imageURL, c.PositionX, c.PositionY, w, h, c.Version+1, c.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM card_tombstones WHERE card_id = ?", c.ID); err != nil {
+		return err
+	}
+
+	publishCard(db, EventCardUpdated, c.ID)
+	return nil
 }
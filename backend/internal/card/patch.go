@@ -0,0 +1,52 @@
+package card
+
+import (
+	"errors"
+	"strings"
+)
+
+var errInvalidKind = errors.New("invalid card kind")
+
+// Patch holds a partial card update; nil fields keep their current value.
+// Text applies to text cards, Width/Height to image cards.
+type Patch struct {
+	Text      *string  `json:"text,omitempty"`
+	PositionX *float64 `json:"position_x,omitempty"`
+	PositionY *float64 `json:"position_y,omitempty"`
+	Width     *float64 `json:"width,omitempty"`
+	Height    *float64 `json:"height,omitempty"`
+}
+
+// PatchCard merges p into an already loaded card and writes it with the
+// update function for the card's kind. version has UpdateCard's semantics.
+func PatchCard(db DBTX, existing Card, p Patch, version int64) (int64, error) {
+	x, y := existing.PositionX, existing.PositionY
+	if p.PositionX != nil {
+		x = *p.PositionX
+	}
+	if p.PositionY != nil {
+		y = *p.PositionY
+	}
+
+	switch strings.ToLower(strings.TrimSpace(existing.Kind)) {
+	case "", "text":
+		text := existing.Text
+		if p.Text != nil {
+			text = *p.Text
+		}
+		return UpdateCard(db, existing.ID, text, x, y, version)
+
+	case "image":
+		width, height := existing.Width, existing.Height
+		if p.Width != nil {
+			width = p.Width
+		}
+		if p.Height != nil {
+			height = p.Height
+		}
+		return UpdateImageCard(db, existing.ID, x, y, width, height, version)
+
+	default:
+		return 0, errInvalidKind
+	}
+}
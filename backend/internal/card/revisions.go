@@ -0,0 +1,395 @@
+package card
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// Revision operations
+const (
+	RevisionCreate = "create"
+	RevisionUpdate = "update"
+	RevisionDelete = "delete"
+	RevisionRevert = "revert"
+	RevisionUndo   = "undo"
+	RevisionRedo   = "redo"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Actor identifies who made a change: a signed-in user or a share link
+type Actor struct {
+	UserID  *int64 `json:"user_id"`
+	ShareID *int64 `json:"share_id"`
+}
+
+func UserActor(userID int64) Actor   { return Actor{UserID: &userID} }
+func ShareActor(shareID int64) Actor { return Actor{ShareID: &shareID} }
+
+// CardState is the content of a card at one point in time
+type CardState struct {
+	Kind      string   `json:"kind"`
+	Text      string   `json:"text,omitempty"`
+	ImageURL  string   `json:"image_url,omitempty"`
+	PositionX float64  `json:"position_x"`
+	PositionY float64  `json:"position_y"`
+	Width     *float64 `json:"width,omitempty"`
+	Height    *float64 `json:"height,omitempty"`
+}
+
+func stateOf(c Card) *CardState {
+	return &CardState{
+		Kind:      c.Kind,
+		Text:      c.Text,
+		ImageURL:  c.ImageURL,
+		PositionX: c.PositionX,
+		PositionY: c.PositionY,
+		Width:     c.Width,
+		Height:    c.Height,
+	}
+}
+
+// signed is a copy of s with its stored image key swapped for a signed URL
+// from store, for sending to a client
+func (s *CardState) signed(ctx context.Context, store storage.Store) *CardState {
+	if s == nil || s.ImageURL == "" {
+		return s
+	}
+	cp := *s
+	cp.ImageURL = storage.SignWith(ctx, store, s.ImageURL)
+	return &cp
+}
+
+// Revision is one recorded change to a card. Before is nil for creates and
+// After is nil for deletes.
+type Revision struct {
+	ID      int64  `json:"id"`
+	CardID  int64  `json:"card_id"`
+	BoardID int64  `json:"board_id"`
+	Op      string `json:"op"` // "create" | "update" | "delete" | "revert" | "undo" | "redo"
+	Actor
+	Before    *CardState `json:"before"`
+	After     *CardState `json:"after"`
+	Version   int64      `json:"version"`             // card version after the change (deletes: the deleted version)
+	RevertOf  *int64     `json:"revert_of,omitempty"` // revision a revert went back to, or an undo/redo replayed
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Revise runs fn in a transaction and records a revision of the card from its
+// state before fn to its state after. cardID is 0 when fn creates the card;
+// fn then returns the new ID, otherwise the number of rows it affected. No
+// revision is written when fn affects nothing or fails.
+func Revise(db *sql.DB, actor Actor, op string, cardID int64, fn func(tx *Tx) (int64, error)) (int64, error) {
+	tx, err := Begin(db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := reviseTx(tx, actor, op, cardID, nil, fn)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, tx.Commit()
+}
+
+func reviseTx(tx *Tx, actor Actor, op string, cardID int64, revertOf *int64, fn func(tx *Tx) (int64, error)) (int64, error) {
+	n, _, err := revise(tx, actor, op, cardID, revertOf, fn)
+	return n, err
+}
+
+// revise is reviseTx that also returns the recorded revision (zero when fn
+// affected nothing). Direct user edits are pushed onto the user's undo stack.
+// Deletes are preceded by a (rate-limited) snapshot of the board.
+func revise(tx *Tx, actor Actor, op string, cardID int64, revertOf *int64, fn func(tx *Tx) (int64, error)) (int64, Revision, error) {
+	var before *CardState
+	var boardID, version int64
+	if op == RevisionDelete && cardID != 0 {
+		// Before the card row is locked, as batches lock the board first too
+		if err := snapshotBeforeDelete(tx, cardID, actor.UserID); err != nil {
+			return 0, Revision{}, err
+		}
+	}
+	if cardID != 0 {
+		// Lock the row so the recorded "before" is what fn actually changed
+		var locked int64
+		err := tx.QueryRow("SELECT id FROM cards WHERE id = ? FOR UPDATE", cardID).Scan(&locked)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, Revision{}, err
+		}
+		c, err := GetCard(tx, cardID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, Revision{}, err
+		}
+		if err == nil {
+			before, boardID, version = stateOf(c), c.BoardID, c.Version
+		}
+	}
+
+	n, err := fn(tx)
+	if err != nil || n == 0 {
+		return n, Revision{}, err
+	}
+
+	id := cardID
+	if id == 0 {
+		id = n
+	}
+	var after *CardState
+	c, err := GetCard(tx, id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, Revision{}, err
+	}
+	if err == nil {
+		after, boardID, version = stateOf(c), c.BoardID, c.Version
+	}
+
+	rev := Revision{
+		CardID:   id,
+		BoardID:  boardID,
+		Op:       op,
+		Actor:    actor,
+		Before:   before,
+		After:    after,
+		Version:  version,
+		RevertOf: revertOf,
+	}
+	rev.ID, err = recordRevision(tx, rev)
+	if err != nil {
+		return 0, Revision{}, err
+	}
+
+	// Cards appearing or disappearing also go in the board's activity log
+	switch {
+	case before == nil && after != nil:
+		err = activity.Record(tx, boardID, actor.UserID, actor.ShareID, activity.TypeCardCreated, cardActivity(id, after))
+	case before != nil && after == nil:
+		err = activity.Record(tx, boardID, actor.UserID, actor.ShareID, activity.TypeCardDeleted, cardActivity(id, before))
+	}
+	if err != nil {
+		return 0, Revision{}, err
+	}
+
+	if actor.UserID != nil && op != RevisionUndo && op != RevisionRedo {
+		if err := pushOperation(tx, *actor.UserID, rev); err != nil {
+			return 0, Revision{}, err
+		}
+	}
+	return n, rev, nil
+}
+
+// cardActivity is the activity log payload for a card created or deleted
+func cardActivity(cardID int64, s *CardState) map[string]interface{} {
+	data := map[string]interface{}{"card_id": cardID}
+	if s != nil {
+		data["kind"] = s.Kind
+	}
+	return data
+}
+
+func recordRevision(db DBTX, rev Revision) (int64, error) {
+	before, err := marshalState(rev.Before)
+	if err != nil {
+		return 0, err
+	}
+	after, err := marshalState(rev.After)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(
+		`INSERT INTO card_revisions (card_id, board_id, op, user_id, share_id, before_state, after_state, version, revert_of)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rev.CardID, rev.BoardID, rev.Op, rev.UserID, rev.ShareID, before, after, rev.Version, rev.RevertOf,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// marshalState encodes a state for a nullable JSON column
+func marshalState(s *CardState) (interface{}, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+const revisionColumns = "id, card_id, board_id, op, user_id, share_id, before_state, after_state, version, revert_of, created_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRevision(row rowScanner) (Revision, error) {
+	var rev Revision
+	var userID, shareID, revertOf sql.NullInt64
+	var before, after []byte
+	if err := row.Scan(&rev.ID, &rev.CardID, &rev.BoardID, &rev.Op, &userID, &shareID, &before, &after, &rev.Version, &revertOf, &rev.CreatedAt); err != nil {
+		return Revision{}, err
+	}
+	if userID.Valid {
+		rev.UserID = &userID.Int64
+	}
+	if shareID.Valid {
+		rev.ShareID = &shareID.Int64
+	}
+	if revertOf.Valid {
+		rev.RevertOf = &revertOf.Int64
+	}
+	if before != nil {
+		rev.Before = &CardState{}
+		if err := json.Unmarshal(before, rev.Before); err != nil {
+			return Revision{}, err
+		}
+	}
+	if after != nil {
+		rev.After = &CardState{}
+		if err := json.Unmarshal(after, rev.After); err != nil {
+			return Revision{}, err
+		}
+	}
+	return rev, nil
+}
+
+// GetRevision fetches one revision of a card
+func GetRevision(db DBTX, cardID, revisionID int64) (Revision, error) {
+	rev, err := scanRevision(db.QueryRow(
+		"SELECT "+revisionColumns+" FROM card_revisions WHERE id = ? AND card_id = ?",
+		revisionID, cardID,
+	))
+	if err == sql.ErrNoRows {
+		return Revision{}, ErrRevisionNotFound
+	}
+	return rev, err
+}
+
+// GetCardHistory lists a card's revisions newest first. before (a revision
+// ID, 0 for the newest) and limit page through long histories.
+func GetCardHistory(db DBTX, cardID, before int64, limit int) ([]Revision, error) {
+	rows, err := db.Query(
+		"SELECT "+revisionColumns+" FROM card_revisions WHERE card_id = ? AND (? = 0 OR id < ?) ORDER BY id DESC LIMIT ?",
+		cardID, before, before, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if revisions == nil {
+		revisions = []Revision{}
+	}
+
+	return revisions, rows.Err()
+}
+
+// SignRevisions swaps the image keys recorded in revs for signed URLs from
+// store, like Card's MarshalJSON does for live cards
+func SignRevisions(ctx context.Context, store storage.Store, revs []Revision) {
+	for i := range revs {
+		revs[i].Before = revs[i].Before.signed(ctx, store)
+		revs[i].After = revs[i].After.signed(ctx, store)
+	}
+}
+
+// CardBoardID finds the board a card is (or was, if deleted) on, so history
+// stays reachable after a delete. Returns sql.ErrNoRows for unknown cards.
+func CardBoardID(db DBTX, cardID int64) (int64, error) {
+	var boardID int64
+	err := db.QueryRow("SELECT board_id FROM cards WHERE id = ?", cardID).Scan(&boardID)
+	if err != sql.ErrNoRows {
+		return boardID, err
+	}
+	err = db.QueryRow("SELECT board_id FROM card_revisions WHERE card_id = ? ORDER BY id DESC LIMIT 1", cardID).Scan(&boardID)
+	return boardID, err
+}
+
+// RevertCard puts a card back into the state recorded by a revision: the
+// state after it, or for a delete the state before it (re-creating the card).
+// version has UpdateCard's semantics and is ignored when the card is deleted.
+// The revert is itself recorded as a revision.
+func RevertCard(db *sql.DB, actor Actor, cardID, revisionID, version int64) error {
+	tx, err := Begin(db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rev, err := GetRevision(tx, cardID, revisionID)
+	if err != nil {
+		return err
+	}
+	target := rev.After
+	if target == nil {
+		target = rev.Before
+	}
+	if target == nil {
+		return ErrRevisionNotFound
+	}
+
+	_, err = reviseTx(tx, actor, RevisionRevert, cardID, &rev.ID, func(tx *Tx) (int64, error) {
+		existing, err := GetCard(tx, cardID)
+		if err == sql.ErrNoRows {
+			return recreateCard(tx, rev.BoardID, cardID, target)
+		}
+		if err != nil {
+			return 0, err
+		}
+		return PatchCard(tx, existing, Patch{
+			Text:      &target.Text,
+			PositionX: &target.PositionX,
+			PositionY: &target.PositionY,
+			Width:     target.Width,
+			Height:    target.Height,
+		}, version)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recreateCard brings a deleted card back under its old ID in the given
+// state, past the last version it had. Returns 1 (rows affected) on success.
+func recreateCard(tx *Tx, boardID, cardID int64, s *CardState) (int64, error) {
+	var last int64
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM card_revisions WHERE card_id = ?", cardID).Scan(&last); err != nil {
+		return 0, err
+	}
+	err := RestoreCard(tx, Card{
+		ID:        cardID,
+		BoardID:   boardID,
+		Kind:      s.Kind,
+		Text:      s.Text,
+		ImageURL:  s.ImageURL,
+		PositionX: s.PositionX,
+		PositionY: s.PositionY,
+		Width:     s.Width,
+		Height:    s.Height,
+		Version:   last,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
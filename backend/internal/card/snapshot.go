@@ -0,0 +1,291 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
+)
+
+// Snapshot reasons
+const (
+	SnapshotManual        = "manual"
+	SnapshotBeforeBatch   = "before_batch"
+	SnapshotBeforeDelete  = "before_delete"
+	SnapshotBeforeRestore = "before_restore"
+)
+
+// Automatic snapshots kept per board; manual ones are never pruned
+const maxAutoSnapshots = 50
+
+// deleteSnapshotGap is the least time between automatic snapshots taken for
+// single deletes, so clearing a board card by card leaves a few snapshots
+// rather than one per card
+const deleteSnapshotGap = time.Minute
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a point-in-time copy of a board's title, cards and links
+type Snapshot struct {
+	ID        int64         `json:"id"`
+	BoardID   int64         `json:"board_id"`
+	Version   int64         `json:"version"` // 1, 2, 3... per board
+	Title     string        `json:"title"`
+	Reason    string        `json:"reason"` // "manual" | "before_batch" | "before_delete" | "before_restore"
+	CreatedBy *int64        `json:"created_by"`
+	CardCount int           `json:"card_count"`
+	CreatedAt time.Time     `json:"created_at"`
+	Data      *SnapshotData `json:"data,omitempty"` // only when fetching a single snapshot
+}
+
+type SnapshotData struct {
+	Cards []Card `json:"cards"`
+	Links []Link `json:"links"`
+}
+
+// storedCard is a Card without its MarshalJSON, for writing to the database
+type storedCard Card
+
+// CreateSnapshot captures the board as it currently is. createdBy is nil for
+// share-link guests. Run it inside the transaction of the operation it guards;
+// given a *sql.DB it opens its own.
+func CreateSnapshot(db DBTX, boardID int64, createdBy *int64, reason string) (Snapshot, error) {
+	var snapshot Snapshot
+	err := withTx(db, func(tx DBTX) error {
+		var err error
+		snapshot, err = createSnapshot(tx, boardID, createdBy, reason)
+		return err
+	})
+	return snapshot, err
+}
+
+func createSnapshot(db DBTX, boardID int64, createdBy *int64, reason string) (Snapshot, error) {
+	// Locking the board makes concurrent snapshots take turns at the next
+	// version number instead of colliding on it
+	var title string
+	if err := db.QueryRow("SELECT title FROM boards WHERE id = ? FOR UPDATE", boardID).Scan(&title); err != nil {
+		return Snapshot{}, err
+	}
+	cards, err := GetCardsByBoard(db, boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	links, err := GetLinksByBoard(db, boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	// Store the raw keys; Card's MarshalJSON would write expiring URLs
+	stored := struct {
+		Cards []storedCard `json:"cards"`
+		Links []Link       `json:"links"`
+	}{Cards: make([]storedCard, len(cards)), Links: links}
+	for i, c := range cards {
+		stored.Cards[i] = storedCard(c)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	res, err := db.Exec(
+		`INSERT INTO board_snapshots (board_id, version, title, reason, created_by, card_count, data)
+         SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, ? FROM board_snapshots WHERE board_id = ?`,
+		boardID, title, reason, createdBy, len(cards), data, boardID,
+	)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if reason != SnapshotManual {
+		if err := pruneAutoSnapshots(db, boardID); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	return GetSnapshot(db, boardID, id, false)
+}
+
+// snapshotBeforeDelete takes a "before_delete" snapshot of the board a card
+// is on, unless it already has an automatic snapshot from the last
+// deleteSnapshotGap (a batch's, or one for an earlier delete)
+func snapshotBeforeDelete(db DBTX, cardID int64, createdBy *int64) error {
+	var boardID int64
+	err := db.QueryRow("SELECT board_id FROM cards WHERE id = ?", cardID).Scan(&boardID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var recent bool
+	err = db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM board_snapshots WHERE board_id = ? AND reason <> ? AND created_at > NOW() - INTERVAL ? SECOND)",
+		boardID, SnapshotManual, int(deleteSnapshotGap.Seconds()),
+	).Scan(&recent)
+	if err != nil || recent {
+		return err
+	}
+	_, err = createSnapshot(db, boardID, createdBy, SnapshotBeforeDelete)
+	return err
+}
+
+// pruneAutoSnapshots keeps only the newest maxAutoSnapshots automatic snapshots
+func pruneAutoSnapshots(db DBTX, boardID int64) error {
+	_, err := db.Exec(
+		`DELETE FROM board_snapshots
+         WHERE board_id = ? AND reason <> ? AND id NOT IN (
+             SELECT id FROM (
+                 SELECT id FROM board_snapshots WHERE board_id = ? AND reason <> ? ORDER BY version DESC LIMIT ?
+             ) AS keep
+         )`,
+		boardID, SnapshotManual, boardID, SnapshotManual, maxAutoSnapshots,
+	)
+	return err
+}
+
+// GetSnapshot fetches one snapshot of a board, optionally with its cards and links
+func GetSnapshot(db DBTX, boardID, snapshotID int64, withData bool) (Snapshot, error) {
+	var s Snapshot
+	var createdBy sql.NullInt64
+	var data []byte
+	err := db.QueryRow(
+		"SELECT id, board_id, version, title, reason, created_by, card_count, created_at, data FROM board_snapshots WHERE id = ? AND board_id = ?",
+		snapshotID, boardID,
+	).Scan(&s.ID, &s.BoardID, &s.Version, &s.Title, &s.Reason, &createdBy, &s.CardCount, &s.CreatedAt, &data)
+	if err == sql.ErrNoRows {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if createdBy.Valid {
+		s.CreatedBy = &createdBy.Int64
+	}
+	if withData {
+		s.Data = &SnapshotData{}
+		if err := json.Unmarshal(data, s.Data); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	return s, nil
+}
+
+// GetSnapshots lists a board's snapshots, newest first, without their data
+func GetSnapshots(db DBTX, boardID int64) ([]Snapshot, error) {
+	rows, err := db.Query(
+		"SELECT id, board_id, version, title, reason, created_by, card_count, created_at FROM board_snapshots WHERE board_id = ? ORDER BY version DESC",
+		boardID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	for rows.Next() {
+		var s Snapshot
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&s.ID, &s.BoardID, &s.Version, &s.Title, &s.Reason, &createdBy, &s.CardCount, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			s.CreatedBy = &createdBy.Int64
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	if snapshots == nil {
+		snapshots = []Snapshot{}
+	}
+
+	return snapshots, nil
+}
+
+// RestoreSnapshot rolls the board's title, cards and links back to a snapshot
+// in one transaction, after taking a "before_restore" snapshot of the current
+// state. Cards created since the snapshot are deleted (with tombstones); their
+// stored images are kept so the safety snapshot can bring them back. Image
+// objects deleted since the snapshot was taken are not recovered.
+func RestoreSnapshot(db *sql.DB, boardID, snapshotID, userID int64) (Snapshot, error) {
+	tx, err := Begin(db)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer tx.Rollback()
+
+	target, err := GetSnapshot(tx, boardID, snapshotID, true)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	safety, err := CreateSnapshot(tx, boardID, &userID, SnapshotBeforeRestore)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if _, err := tx.Exec("UPDATE boards SET title = ? WHERE id = ?", target.Title, boardID); err != nil {
+		return Snapshot{}, err
+	}
+
+	// Drop cards that did not exist in the snapshot
+	keep := make(map[int64]bool, len(target.Data.Cards))
+	for _, c := range target.Data.Cards {
+		keep[c.ID] = true
+	}
+	current, err := GetCardsByBoard(tx, boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, c := range current {
+		if keep[c.ID] {
+			continue
+		}
+		if _, err := DeleteCard(tx, c.ID); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	for _, c := range target.Data.Cards {
+		c.BoardID = boardID
+		if err := RestoreCard(tx, c); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	// Links are replaced wholesale, keeping their IDs
+	if _, err := tx.Exec("DELETE FROM card_links WHERE board_id = ?", boardID); err != nil {
+		return Snapshot{}, err
+	}
+	for _, l := range target.Data.Links {
+		if _, err := tx.Exec(
+			"INSERT INTO card_links (id, board_id, source_card_id, target_card_id, label, style, directed, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			l.ID, boardID, l.SourceCardID, l.TargetCardID, l.Label, l.Style, l.Directed, l.CreatedAt,
+		); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	err = activity.Record(tx, boardID, &userID, nil, activity.TypeBoardRestored, map[string]interface{}{
+		"snapshot_id":      target.ID,
+		"snapshot_version": target.Version,
+		"backup_snapshot":  safety.ID,
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	// Tell connected clients to reload rather than replaying every link change
+	if publisher != nil {
+		emit(tx, Event{Type: EventBoardRestored, BoardID: boardID})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Snapshot{}, err
+	}
+	return safety, nil
+}
@@ -0,0 +1,141 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+type SnapshotHandler struct {
+	DB *sql.DB
+}
+
+// Routes handled:
+// - GET  /boards/{id}/snapshots                 (any access)
+// - POST /boards/{id}/snapshots                 (owner or edit)
+// - GET  /boards/{id}/snapshots/{sid}           (any access; includes cards and links)
+// - POST /boards/{id}/snapshots/{sid}/restore   (owner only)
+func (h *SnapshotHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 || parts[1] != "boards" || parts[3] != "snapshots" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// --- Collection: /boards/{id}/snapshots ---
+	if len(parts) == 4 || (len(parts) == 5 && parts[4] == "") {
+		switch r.Method {
+		case http.MethodGet:
+			snapshots, err := GetSnapshots(h.DB, boardID)
+			if err != nil {
+				middleware.JSONError(w, "Failed to fetch snapshots", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(snapshots)
+
+		case http.MethodPost:
+			if perm != board.PermissionOwner && perm != board.PermissionEdit {
+				middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			snapshot, err := CreateSnapshot(h.DB, boardID, &userID, SnapshotManual)
+			if err != nil {
+				log.Printf("snapshot of board %d failed: %v", boardID, err)
+				middleware.JSONError(w, "Failed to create snapshot", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(snapshot)
+
+		default:
+			middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	snapshotID, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid snapshot ID", http.StatusBadRequest)
+		return
+	}
+
+	// --- Restore: /boards/{id}/snapshots/{sid}/restore ---
+	if len(parts) == 6 && parts[5] == "restore" {
+		if r.Method != http.MethodPost {
+			middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if perm != board.PermissionOwner {
+			middleware.JSONError(w, "Only the board owner can restore snapshots", http.StatusForbidden)
+			return
+		}
+
+		safety, err := RestoreSnapshot(h.DB, boardID, snapshotID, userID)
+		if err == ErrSnapshotNotFound {
+			middleware.JSONError(w, "Snapshot not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("restore of board %d to snapshot %d failed: %v", boardID, snapshotID, err)
+			middleware.JSONError(w, "Failed to restore snapshot", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":          "restored",
+			"backup_snapshot": safety, // state just before the restore
+		})
+		return
+	}
+
+	// --- Single snapshot: /boards/{id}/snapshots/{sid} ---
+	if len(parts) != 5 {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	snapshot, err := GetSnapshot(h.DB, boardID, snapshotID, true)
+	if err == ErrSnapshotNotFound {
+		middleware.JSONError(w, "Snapshot not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch snapshot", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
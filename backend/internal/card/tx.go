@@ -0,0 +1,68 @@
+package card
+
+import (
+	"database/sql"
+)
+
+// DBTX is satisfied by *sql.DB, *sql.Tx and *Tx, so the model functions can
+// run standalone or as part of a larger transaction.
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction that holds back card events until it commits, so
+// subscribers never see changes that were rolled back.
+type Tx struct {
+	*sql.Tx
+	events []Event
+}
+
+func Begin(db *sql.DB) (*Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// Commit commits the transaction and then publishes its queued events
+func (t *Tx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	events := t.events
+	t.events = nil
+	if publisher != nil {
+		for _, ev := range events {
+			publisher.Publish(ev)
+		}
+	}
+	return nil
+}
+
+// Rollback aborts the transaction and drops its queued events
+func (t *Tx) Rollback() error {
+	t.events = nil
+	return t.Tx.Rollback()
+}
+
+// withTx runs fn inside db when it is already a transaction, otherwise in a
+// new transaction that is committed when fn succeeds.
+func withTx(db DBTX, fn func(tx DBTX) error) error {
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,179 @@
+package card
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Undo history kept per user per board
+const maxUndoDepth = 100
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// UndoResult describes the card an undo or redo touched. Card is nil when
+// the card no longer exists afterwards (an undone create or a redone delete).
+type UndoResult struct {
+	CardID int64  `json:"card_id"`
+	Op     string `json:"op"` // the original operation: "create" | "update" | "delete" | "revert"
+	Card   *Card  `json:"card"`
+}
+
+// pushOperation puts a user's edit on their undo stack for the board. A new
+// edit clears the redo side of the stack, like in any editor.
+func pushOperation(tx *Tx, userID int64, rev Revision) error {
+	if _, err := tx.Exec(
+		"DELETE FROM card_operations WHERE board_id = ? AND user_id = ? AND undone = TRUE",
+		rev.BoardID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO card_operations (board_id, user_id, card_id, revision_id) VALUES (?, ?, ?, ?)",
+		rev.BoardID, userID, rev.CardID, rev.ID,
+	); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		`DELETE FROM card_operations
+         WHERE board_id = ? AND user_id = ? AND id NOT IN (
+             SELECT id FROM (
+                 SELECT id FROM card_operations WHERE board_id = ? AND user_id = ? ORDER BY id DESC LIMIT ?
+             ) AS keep
+         )`,
+		rev.BoardID, userID, rev.BoardID, userID, maxUndoDepth,
+	)
+	return err
+}
+
+// Undo reverts the user's most recent not-yet-undone edit on the board by
+// applying its inverse. If anyone has changed the card since, the entry can
+// never be undone safely, so it is dropped from the stack and
+// ErrVersionConflict is returned with the affected card ID.
+func Undo(db *sql.DB, boardID, userID int64) (UndoResult, error) {
+	return step(db, boardID, userID, true)
+}
+
+// Redo re-applies the user's most recently undone edit, under the same
+// conflict rules as Undo
+func Redo(db *sql.DB, boardID, userID int64) (UndoResult, error) {
+	return step(db, boardID, userID, false)
+}
+
+func step(db *sql.DB, boardID, userID int64, undo bool) (UndoResult, error) {
+	tx, err := Begin(db)
+	if err != nil {
+		return UndoResult{}, err
+	}
+	defer tx.Rollback()
+
+	// Undo takes the newest live entry; redo the entry undone last, which is
+	// the oldest undone one
+	query := "SELECT id, card_id, revision_id, undo_revision_id FROM card_operations WHERE board_id = ? AND user_id = ? AND undone = FALSE ORDER BY id DESC LIMIT 1 FOR UPDATE"
+	empty := ErrNothingToUndo
+	if !undo {
+		query = "SELECT id, card_id, revision_id, undo_revision_id FROM card_operations WHERE board_id = ? AND user_id = ? AND undone = TRUE ORDER BY id ASC LIMIT 1 FOR UPDATE"
+		empty = ErrNothingToRedo
+	}
+	var opID, cardID, revisionID int64
+	var undoRevisionID sql.NullInt64
+	err = tx.QueryRow(query, boardID, userID).Scan(&opID, &cardID, &revisionID, &undoRevisionID)
+	if err == sql.ErrNoRows {
+		return UndoResult{}, empty
+	}
+	if err != nil {
+		return UndoResult{}, err
+	}
+
+	forward, err := GetRevision(tx, cardID, revisionID)
+	if err != nil {
+		return UndoResult{}, err
+	}
+
+	// Undo goes from the forward revision's "after" back to its "before"; redo
+	// from the state the undo left behind to "after" again
+	expected, from, target := forward.Version, forward.After, forward.Before
+	if !undo {
+		if !undoRevisionID.Valid {
+			return UndoResult{}, ErrNothingToRedo
+		}
+		inverse, err := GetRevision(tx, cardID, undoRevisionID.Int64)
+		if err != nil {
+			return UndoResult{}, err
+		}
+		expected, from, target = inverse.Version, inverse.After, forward.After
+	}
+
+	revOp := RevisionUndo
+	if !undo {
+		revOp = RevisionRedo
+	}
+	_, applied, err := revise(tx, UserActor(userID), revOp, cardID, &forward.ID, func(tx *Tx) (int64, error) {
+		return applyState(tx, boardID, cardID, expected, from, target)
+	})
+	if err == ErrVersionConflict {
+		// Permanent: versions only grow, so drop the entry and report it
+		tx.Rollback()
+		if _, derr := db.Exec("DELETE FROM card_operations WHERE id = ?", opID); derr != nil {
+			return UndoResult{}, derr
+		}
+		return UndoResult{CardID: cardID, Op: forward.Op}, ErrVersionConflict
+	}
+	if err != nil {
+		return UndoResult{}, err
+	}
+
+	if undo {
+		_, err = tx.Exec("UPDATE card_operations SET undone = TRUE, undo_revision_id = ? WHERE id = ?", applied.ID, opID)
+	} else {
+		_, err = tx.Exec("UPDATE card_operations SET undone = FALSE, undo_revision_id = NULL WHERE id = ?", opID)
+	}
+	if err != nil {
+		return UndoResult{}, err
+	}
+
+	result := UndoResult{CardID: cardID, Op: forward.Op}
+	if c, err := GetCard(tx, cardID); err == nil {
+		result.Card = &c
+	} else if err != sql.ErrNoRows {
+		return UndoResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return UndoResult{}, err
+	}
+	return result, nil
+}
+
+// applyState moves a card from one recorded state to another through the
+// regular model functions. from == nil means the card should not exist yet;
+// otherwise it must still be at version expected. target == nil deletes it.
+func applyState(tx *Tx, boardID, cardID, expected int64, from, target *CardState) (int64, error) {
+	current, err := GetCard(tx, cardID)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if exists != (from != nil) || (exists && current.Version != expected) {
+		return 0, ErrVersionConflict
+	}
+
+	switch {
+	case target == nil:
+		return DeleteCard(tx, cardID)
+
+	case !exists:
+		return recreateCard(tx, boardID, cardID, target)
+
+	default:
+		return PatchCard(tx, current, Patch{
+			Text:      &target.Text,
+			PositionX: &target.PositionX,
+			PositionY: &target.PositionY,
+			Width:     target.Width,
+			Height:    target.Height,
+		}, expected)
+	}
+}
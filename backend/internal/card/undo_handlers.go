@@ -0,0 +1,96 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+type UndoHandler struct {
+	DB *sql.DB
+}
+
+// Routes handled:
+// - POST /boards/{id}/undo
+// - POST /boards/{id}/redo
+// perms: owner or edit. Each user has their own stack per board.
+func (h *UndoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 4 || parts[1] != "boards" || (parts[3] != "undo" && parts[3] != "redo") {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm != board.PermissionOwner && perm != board.PermissionEdit {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var result UndoResult
+	status := "undone"
+	if parts[3] == "undo" {
+		result, err = Undo(h.DB, boardID, userID)
+	} else {
+		result, err = Redo(h.DB, boardID, userID)
+		status = "redone"
+	}
+
+	switch err {
+	case nil:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  status,
+			"card_id": result.CardID,
+			"op":      result.Op,
+			"card":    result.Card,
+		})
+
+	case ErrNothingToUndo, ErrNothingToRedo:
+		middleware.JSONError(w, err.Error(), http.StatusNotFound)
+
+	case ErrVersionConflict:
+		// The entry was dropped; the next request moves on to the one before it
+		resp := map[string]interface{}{
+			"error":   "card was modified by someone else since; this step was skipped",
+			"card_id": result.CardID,
+			"op":      result.Op,
+		}
+		if c, err := GetCard(h.DB, result.CardID); err == nil {
+			resp["card"] = c
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(resp)
+
+	default:
+		log.Printf("%s on board %d failed: %v", parts[3], boardID, err)
+		middleware.JSONError(w, "Failed to "+parts[3], http.StatusInternalServerError)
+	}
+}
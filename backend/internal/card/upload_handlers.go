@@ -0,0 +1,132 @@
+package card
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// ServeImageUpload handles a multipart image upload (file=<file>) for a
+// board the caller may edit. Shared by the board and share-link routes.
+// Responds with {"url": "..."}, a signed URL to reference from a card.
+func ServeImageUpload(w http.ResponseWriter, r *http.Request, db *sql.DB, store storage.Store, boardID int64, actor Actor) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImageBytes+1<<20) // room for the form around the file
+	if err := r.ParseMultipartForm(MaxImageBytes); err != nil {
+		middleware.JSONError(w, "Invalid form data or file too large", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		middleware.JSONError(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxImageBytes+1))
+	if err != nil {
+		middleware.JSONError(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+	if len(data) > MaxImageBytes {
+		middleware.JSONError(w, "File too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The type comes from the bytes; the client's filename and header are ignored
+	key, err := StoreImage(r.Context(), db, store, boardID, actor, data)
+	if err == imageproc.ErrUnsupported || err == imageproc.ErrTooLarge {
+		middleware.JSONError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err == quota.ErrQuotaExceeded {
+		middleware.JSONError(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	if err != nil {
+		log.Printf("image upload on board %d failed: %v", boardID, err)
+		middleware.JSONError(w, "Failed to upload image", http.StatusInternalServerError)
+		return
+	}
+
+	url, err := store.SignedURL(r.Context(), key, storage.URLTTL)
+	if err != nil {
+		middleware.JSONError(w, "Failed to sign image URL", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": url})
+}
+
+// ServePresign handles POST .../images/presign for a board the caller may
+// edit. Shared by the board and share-link routes.
+//
+//	body: {"content_type": "image/png", "size": 12345}
+func ServePresign(w http.ResponseWriter, r *http.Request, db *sql.DB, store storage.Store, boardID int64, actor Actor) {
+	var body struct {
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := PresignImageUpload(r.Context(), db, store, boardID, actor, body.ContentType, body.Size)
+	if rejected, ok := err.(*UploadRejectedError); ok {
+		middleware.JSONError(w, rejected.Reason, http.StatusBadRequest)
+		return
+	}
+	if err == quota.ErrQuotaExceeded {
+		middleware.JSONError(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	if err != nil {
+		log.Printf("presign upload on board %d failed: %v", boardID, err)
+		middleware.JSONError(w, "Failed to create upload URL", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(upload)
+}
+
+// ServeConfirm handles POST .../images/confirm once the client has uploaded
+// to a presigned URL. Responds like the multipart upload: {"url": "..."}.
+//
+//	body: {"key": "images/12/<uuid>.png"}
+func ServeConfirm(w http.ResponseWriter, r *http.Request, db *sql.DB, store storage.Store, boardID int64) {
+	var body struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Key == "" {
+		middleware.JSONError(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	url, err := ConfirmImageUpload(r.Context(), db, store, boardID, body.Key)
+	if rejected, ok := err.(*UploadRejectedError); ok {
+		middleware.JSONError(w, rejected.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+	switch err {
+	case nil:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"url": url})
+	case ErrUploadNotFound:
+		middleware.JSONError(w, err.Error(), http.StatusNotFound)
+	case ErrUploadMissing:
+		middleware.JSONError(w, err.Error(), http.StatusConflict)
+	case quota.ErrQuotaExceeded:
+		middleware.JSONError(w, err.Error(), http.StatusPaymentRequired)
+	default:
+		log.Printf("confirm upload %s failed: %v", body.Key, err)
+		middleware.JSONError(w, "Failed to confirm upload", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,297 @@
+package card
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// MaxImageBytes caps a single card image
+const MaxImageBytes = 10 << 20
+
+// How long a presigned upload URL stays valid
+const presignTTL = 15 * time.Minute
+
+// Upload states
+const (
+	UploadPending   = "pending"
+	UploadConfirmed = "confirmed"
+	UploadRejected  = "rejected"
+)
+
+var (
+	ErrUploadNotFound    = errors.New("upload not found")
+	ErrUploadMissing     = errors.New("file has not been uploaded yet")
+	ErrImageNotConfirmed = errors.New("image upload has not been confirmed for this board")
+)
+
+// UploadRejectedError means the uploaded object failed validation and was deleted
+type UploadRejectedError struct {
+	Reason string
+}
+
+func (e *UploadRejectedError) Error() string {
+	return "upload rejected: " + e.Reason
+}
+
+// PresignedUpload is what a client needs to PUT an image straight to storage
+type PresignedUpload struct {
+	Key string `json:"key"`
+	storage.PresignedPut
+}
+
+// PresignImageUpload reserves a key under images/{boardID}/ and returns a
+// URL the client can upload exactly size bytes to directly. The image cannot
+// be put on a card until ConfirmImageUpload has checked what actually
+// arrived.
+func PresignImageUpload(ctx context.Context, db *sql.DB, store storage.Store, boardID int64, actor Actor, contentType string, size int64) (PresignedUpload, error) {
+	ext, ok := imageproc.Extension(contentType)
+	if !ok {
+		return PresignedUpload{}, &UploadRejectedError{Reason: "unsupported content type " + contentType}
+	}
+	if size <= 0 || size > MaxImageBytes {
+		return PresignedUpload{}, &UploadRejectedError{Reason: fmt.Sprintf("size must be between 1 and %d bytes", MaxImageBytes)}
+	}
+	if err := quota.Check(db, boardID, size); err != nil {
+		return PresignedUpload{}, err
+	}
+
+	key := fmt.Sprintf("images/%d/%s%s", boardID, uuid.New().String(), ext)
+	put, err := store.PresignPut(ctx, key, contentType, size, presignTTL)
+	if err != nil {
+		return PresignedUpload{}, err
+	}
+	_, err = db.Exec(
+		`INSERT INTO image_uploads (board_id, storage_key, content_type, user_id, share_id, expires_at)
+         VALUES (?, ?, ?, ?, ?, ?)`,
+		boardID, key, contentType, actor.UserID, actor.ShareID, put.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return PresignedUpload{}, err
+	}
+	return PresignedUpload{Key: key, PresignedPut: put}, nil
+}
+
+// ConfirmImageUpload checks that a presigned upload arrived with an allowed
+// size and type, moves the cleaned copy to its content key and returns a
+// signed URL cards may reference. Objects that fail are deleted and the
+// upload is marked rejected. Confirming twice is harmless.
+func ConfirmImageUpload(ctx context.Context, db *sql.DB, store storage.Store, boardID int64, key string) (string, error) {
+	var contentType, status string
+	var objectKey sql.NullString
+	var expiresAt time.Time
+	err := db.QueryRow(
+		"SELECT content_type, status, object_key, expires_at FROM image_uploads WHERE storage_key = ? AND board_id = ?",
+		key, boardID,
+	).Scan(&contentType, &status, &objectKey, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", ErrUploadNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case UploadConfirmed:
+		return store.SignedURL(ctx, objectKey.String, storage.URLTTL)
+	case UploadRejected:
+		return "", &UploadRejectedError{Reason: "upload was already rejected"}
+	}
+
+	info, err := store.Stat(ctx, key)
+	if err == storage.ErrNotFound {
+		if time.Now().After(expiresAt) {
+			return "", rejectUpload(ctx, db, store, key, "upload URL expired before the file arrived")
+		}
+		return "", ErrUploadMissing
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Size == 0 || info.Size > MaxImageBytes {
+		return "", rejectUpload(ctx, db, store, key, fmt.Sprintf("size must be between 1 and %d bytes", MaxImageBytes))
+	}
+
+	data, err := readObject(ctx, store, key)
+	if err != nil {
+		return "", err
+	}
+	// Trust the bytes, not the header the client sent
+	if sniffed, _ := imageproc.Sniff(data); sniffed != contentType {
+		return "", rejectUpload(ctx, db, store, key, "file content is "+sniffed+", expected "+contentType)
+	}
+	res, err := imageproc.Process(data)
+	if err == imageproc.ErrUnsupported || err == imageproc.ErrTooLarge {
+		return "", rejectUpload(ctx, db, store, key, err.Error())
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := quota.Check(db, boardID, res.Size()); err != nil {
+		if err == quota.ErrQuotaExceeded {
+			rejectUpload(ctx, db, store, key, err.Error())
+		}
+		return "", err
+	}
+
+	// Keep the cleaned copy under its content key; what the client sent goes
+	objectKey.String, err = putObject(ctx, db, store, res)
+	if err != nil {
+		return "", err
+	}
+	result, err := db.Exec(
+		"UPDATE image_uploads SET status = ?, size = ?, object_key = ? WHERE storage_key = ? AND status = ?",
+		UploadConfirmed, res.Size(), objectKey.String, key, UploadPending,
+	)
+	if err != nil {
+		return "", err
+	}
+	// Only the request that flipped the status counts the bytes
+	if n, err := result.RowsAffected(); err == nil && n == 1 {
+		if err := quota.AddImageBytes(db, boardID, res.Size()); err != nil {
+			return "", err
+		}
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		log.Printf("WARN: failed to delete confirmed upload %s: %v", key, err)
+	}
+	return store.SignedURL(ctx, objectKey.String, storage.URLTTL)
+}
+
+// StoreImage runs an image uploaded through the API itself through the
+// pipeline and stores the cleaned original and its variants under their
+// content key, counting them against the owner's quota. Returns the
+// original's key.
+//
+// The quota counts what each board uploaded, so an image stored once still
+// counts for every board that uploaded it.
+func StoreImage(ctx context.Context, db *sql.DB, store storage.Store, boardID int64, actor Actor, data []byte) (string, error) {
+	res, err := imageproc.Process(data)
+	if err != nil {
+		return "", err
+	}
+	if err := quota.Check(db, boardID, res.Size()); err != nil {
+		return "", err
+	}
+	key, err := putObject(ctx, db, store, res)
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(
+		`INSERT INTO image_uploads (board_id, storage_key, object_key, content_type, size, status, user_id, share_id, expires_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		boardID, key, key, res.Original.ContentType, res.Size(),
+		UploadConfirmed, actor.UserID, actor.ShareID,
+	)
+	if err != nil {
+		return "", err
+	}
+	if err := quota.AddImageBytes(db, boardID, res.Size()); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// storeProcessed writes an original under key and its variants next to it
+func storeProcessed(ctx context.Context, store storage.Store, key string, res *imageproc.Result) error {
+	if err := store.Put(ctx, key, bytes.NewReader(res.Original.Data), res.Original.ContentType); err != nil {
+		return err
+	}
+	for size, v := range res.Variants {
+		if err := store.Put(ctx, imageproc.VariantKey(key, size), bytes.NewReader(v.Data), v.ContentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// variantsJSON is the image_objects.variants value: {"256": key, ...}, or
+// NULL when the image is smaller than every variant
+func variantsJSON(key string, res *imageproc.Result) (interface{}, error) {
+	if len(res.Variants) == 0 {
+		return nil, nil
+	}
+	m := map[string]string{}
+	for size := range res.Variants {
+		m[strconv.Itoa(size)] = imageproc.VariantKey(key, size)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// attachVariants fills in Variants for the image cards among cards
+func attachVariants(db DBTX, cards []Card) error {
+	var keys []interface{}
+	index := map[string][]int{}
+	for i, c := range cards {
+		if c.Kind != "image" || !storage.IsKey(c.ImageURL) {
+			continue
+		}
+		if _, seen := index[c.ImageURL]; !seen {
+			keys = append(keys, c.ImageURL)
+		}
+		index[c.ImageURL] = append(index[c.ImageURL], i)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(
+		"SELECT storage_key, variants FROM image_objects WHERE variants IS NOT NULL AND storage_key IN (?"+strings.Repeat(", ?", len(keys)-1)+")",
+		keys...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var raw []byte
+		if err := rows.Scan(&key, &raw); err != nil {
+			return err
+		}
+		var variants map[string]string
+		if err := json.Unmarshal(raw, &variants); err != nil {
+			return err
+		}
+		for _, i := range index[key] {
+			cards[i].Variants = variants
+		}
+	}
+	return rows.Err()
+}
+
+// readObject reads a whole stored object, up to MaxImageBytes
+func readObject(ctx context.Context, store storage.Store, key string) ([]byte, error) {
+	obj, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(io.LimitReader(obj.Body, MaxImageBytes+1))
+}
+
+func rejectUpload(ctx context.Context, db *sql.DB, store storage.Store, key, reason string) error {
+	if err := store.Delete(ctx, key); err != nil {
+		return err
+	}
+	if _, err := db.Exec("UPDATE image_uploads SET status = ? WHERE storage_key = ?", UploadRejected, key); err != nil {
+		return err
+	}
+	return &UploadRejectedError{Reason: reason}
+}
@@ -0,0 +1 @@
+ALTER TABLE cards ADD COLUMN version INT NOT NULL DEFAULT 1 AFTER height;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS card_tombstones;
+
+ALTER TABLE cards
+    MODIFY created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    MODIFY updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
@@ -0,0 +1,11 @@
+ALTER TABLE cards
+    MODIFY created_at TIMESTAMP(6) NULL DEFAULT CURRENT_TIMESTAMP(6),
+    MODIFY updated_at TIMESTAMP(6) NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6);
+
+CREATE TABLE card_tombstones (
+    card_id BIGINT PRIMARY KEY,
+    board_id BIGINT NOT NULL,
+    deleted_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_card_tombstones_board (board_id, deleted_at),
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE card_links (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    board_id BIGINT NOT NULL,
+    source_card_id BIGINT NOT NULL,
+    target_card_id BIGINT NOT NULL,
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    style VARCHAR(16) NOT NULL DEFAULT 'solid',
+    directed BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+    FOREIGN KEY (source_card_id) REFERENCES cards(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_card_id) REFERENCES cards(id) ON DELETE CASCADE
+);
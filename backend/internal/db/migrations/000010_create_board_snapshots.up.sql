@@ -0,0 +1,14 @@
+CREATE TABLE board_snapshots (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    board_id BIGINT NOT NULL,
+    version INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    reason VARCHAR(32) NOT NULL DEFAULT 'manual',
+    created_by BIGINT NULL,
+    card_count INT NOT NULL DEFAULT 0,
+    data JSON NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_board_version (board_id, version),
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
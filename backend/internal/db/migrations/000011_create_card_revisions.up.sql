@@ -0,0 +1,16 @@
+CREATE TABLE card_revisions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    card_id BIGINT NOT NULL,
+    board_id BIGINT NOT NULL,
+    op VARCHAR(16) NOT NULL,
+    user_id BIGINT NULL,
+    share_id BIGINT NULL,
+    before_state JSON NULL,
+    after_state JSON NULL,
+    version BIGINT NOT NULL DEFAULT 0,
+    revert_of BIGINT NULL,
+    created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_card_revisions_card (card_id, id),
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,12 @@
+CREATE TABLE board_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    board_id BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    actor_user_id BIGINT NULL,
+    actor_share_id BIGINT NULL,
+    data JSON NULL,
+    created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_board_events_board (board_id, id),
+    INDEX idx_board_events_type (board_id, type, id),
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE card_operations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    board_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    card_id BIGINT NOT NULL,
+    revision_id BIGINT NOT NULL,
+    undo_revision_id BIGINT NULL,
+    undone BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_card_operations_stack (board_id, user_id, undone, id),
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (revision_id) REFERENCES card_revisions(id) ON DELETE CASCADE
+);
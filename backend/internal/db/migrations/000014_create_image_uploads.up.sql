@@ -0,0 +1,16 @@
+CREATE TABLE image_uploads (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    board_id BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NULL,
+    status ENUM('pending', 'confirmed', 'rejected') NOT NULL DEFAULT 'pending',
+    user_id BIGINT NULL,
+    share_id BIGINT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    UNIQUE KEY uq_image_uploads_key (storage_key),
+    INDEX idx_image_uploads_status (status, expires_at),
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,3 @@
+-- One-way: rebuilding URLs would need the bucket and region. Nothing to undo,
+-- since the API still signs full URLs that point into its own store.
+SELECT 1;
@@ -0,0 +1,9 @@
+UPDATE cards
+SET image_url = SUBSTRING(image_url, LOCATE('/images/', image_url) + 1), updated_at = updated_at
+WHERE image_url LIKE 'https://%.amazonaws.com/images/%'
+   OR image_url LIKE 'http%/files/images/%';
+
+UPDATE boards
+SET thumbnail_url = SUBSTRING(thumbnail_url, LOCATE('/thumbnails/', thumbnail_url) + 1)
+WHERE thumbnail_url LIKE 'https://%.amazonaws.com/thumbnails/%'
+   OR thumbnail_url LIKE 'http%/files/thumbnails/%';
@@ -0,0 +1,4 @@
+ALTER TABLE image_uploads
+    DROP COLUMN variants,
+    DROP COLUMN height,
+    DROP COLUMN width;
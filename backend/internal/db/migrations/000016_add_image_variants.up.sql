@@ -0,0 +1,4 @@
+ALTER TABLE image_uploads
+    ADD COLUMN width INT NULL AFTER size,
+    ADD COLUMN height INT NULL AFTER width,
+    ADD COLUMN variants JSON NULL AFTER height;
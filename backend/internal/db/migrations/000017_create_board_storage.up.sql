@@ -0,0 +1,10 @@
+CREATE TABLE board_storage (
+    board_id BIGINT PRIMARY KEY,
+    image_bytes BIGINT NOT NULL DEFAULT 0,
+    thumbnail_bytes BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE
+);
+
+INSERT INTO board_storage (board_id, image_bytes)
+SELECT board_id, SUM(size) FROM image_uploads WHERE status = 'confirmed' AND size IS NOT NULL GROUP BY board_id;
@@ -0,0 +1,16 @@
+ALTER TABLE image_uploads
+    ADD COLUMN width INT NULL AFTER size,
+    ADD COLUMN height INT NULL AFTER width,
+    ADD COLUMN variants JSON NULL AFTER height;
+
+UPDATE image_uploads u
+JOIN image_objects o ON o.storage_key = u.object_key
+SET u.width = o.width, u.height = o.height, u.variants = o.variants;
+
+ALTER TABLE image_uploads
+    DROP INDEX idx_image_uploads_object,
+    DROP COLUMN object_key,
+    DROP INDEX idx_image_uploads_key,
+    ADD UNIQUE KEY uq_image_uploads_key (storage_key);
+
+DROP TABLE IF EXISTS image_objects;
@@ -0,0 +1,34 @@
+CREATE TABLE image_objects (
+    storage_key VARCHAR(255) PRIMARY KEY,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    width INT NULL,
+    height INT NULL,
+    variants JSON NULL,
+    ref_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO image_objects (storage_key, content_type, size, width, height, variants, ref_count)
+SELECT u.storage_key, u.content_type, COALESCE(u.size, 0), u.width, u.height, u.variants,
+       (SELECT COUNT(*) FROM cards c WHERE c.image_url = u.storage_key)
+FROM image_uploads u
+WHERE u.status = 'confirmed';
+
+-- Images uploaded before uploads were recorded
+INSERT IGNORE INTO image_objects (storage_key, ref_count)
+SELECT image_url, COUNT(*) FROM cards
+WHERE kind = 'image' AND image_url LIKE 'images/%'
+GROUP BY image_url;
+
+ALTER TABLE image_uploads
+    DROP INDEX uq_image_uploads_key,
+    ADD INDEX idx_image_uploads_key (storage_key),
+    ADD COLUMN object_key VARCHAR(255) NULL AFTER storage_key,
+    ADD INDEX idx_image_uploads_object (object_key),
+    DROP COLUMN variants,
+    DROP COLUMN height,
+    DROP COLUMN width;
+
+UPDATE image_uploads SET object_key = storage_key WHERE status = 'confirmed';
@@ -0,0 +1,3 @@
+ALTER TABLE boards DROP INDEX ft_boards_title;
+
+ALTER TABLE cards DROP INDEX ft_cards_text;
@@ -0,0 +1,3 @@
+ALTER TABLE cards ADD FULLTEXT INDEX ft_cards_text (text);
+
+ALTER TABLE boards ADD FULLTEXT INDEX ft_boards_title (title);
@@ -0,0 +1 @@
+ALTER TABLE cards DROP INDEX idx_cards_board_updated;
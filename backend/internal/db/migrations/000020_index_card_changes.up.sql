@@ -0,0 +1,3 @@
+UPDATE cards SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE cards ADD INDEX idx_cards_board_updated (board_id, updated_at);
@@ -0,0 +1,230 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+)
+
+// Excalidraw scenes (.excalidraw files). Text cards are rectangles with their
+// text bound inside, image cards are image elements with the picture in the
+// scene's files, and links are arrows bound to both cards. Image cards that
+// link elsewhere are rectangles with the link, marked as images in their
+// custom data. Package importer reads scenes back with the same types.
+
+// ExcalidrawScene is a whole .excalidraw file
+type ExcalidrawScene struct {
+	Type     string                    `json:"type"`
+	Version  int                       `json:"version"`
+	Source   string                    `json:"source"`
+	Elements []ExcalidrawElement       `json:"elements"`
+	AppState map[string]interface{}    `json:"appState"`
+	Files    map[string]ExcalidrawFile `json:"files"`
+}
+
+// ExcalidrawFile is a picture embedded in a scene, keyed by FileID
+type ExcalidrawFile struct {
+	MimeType string `json:"mimeType"`
+	ID       string `json:"id"`
+	DataURL  string `json:"dataURL"`
+	Created  int64  `json:"created"`
+}
+
+// ExcalidrawElement holds the fields of every element type this package
+// reads or writes. Numbers are floats so hand-edited scenes still decode.
+type ExcalidrawElement struct {
+	ID              string                 `json:"id"`
+	Type            string                 `json:"type"`
+	X               float64                `json:"x"`
+	Y               float64                `json:"y"`
+	Width           float64                `json:"width"`
+	Height          float64                `json:"height"`
+	Angle           float64                `json:"angle"`
+	StrokeColor     string                 `json:"strokeColor"`
+	BackgroundColor string                 `json:"backgroundColor"`
+	FillStyle       string                 `json:"fillStyle"`
+	StrokeWidth     float64                `json:"strokeWidth"`
+	StrokeStyle     string                 `json:"strokeStyle"`
+	Roughness       float64                `json:"roughness"`
+	Opacity         float64                `json:"opacity"`
+	GroupIDs        []string               `json:"groupIds"`
+	Seed            float64                `json:"seed"`
+	Version         float64                `json:"version"`
+	VersionNonce    float64                `json:"versionNonce"`
+	IsDeleted       bool                   `json:"isDeleted"`
+	BoundElements   []ExcalidrawBinding    `json:"boundElements"`
+	Updated         int64                  `json:"updated"`
+	Link            *string                `json:"link"`
+	Locked          bool                   `json:"locked"`
+	CustomData      map[string]interface{} `json:"customData,omitempty"`
+
+	// text
+	Text          string  `json:"text,omitempty"`
+	OriginalText  string  `json:"originalText,omitempty"`
+	FontSize      float64 `json:"fontSize,omitempty"`
+	FontFamily    float64 `json:"fontFamily,omitempty"`
+	TextAlign     string  `json:"textAlign,omitempty"`
+	VerticalAlign string  `json:"verticalAlign,omitempty"`
+	ContainerID   *string `json:"containerId,omitempty"`
+	LineHeight    float64 `json:"lineHeight,omitempty"`
+
+	// image
+	FileID string `json:"fileId,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// arrow
+	Points         [][2]float64       `json:"points,omitempty"`
+	StartBinding   *ExcalidrawBinding `json:"startBinding,omitempty"`
+	EndBinding     *ExcalidrawBinding `json:"endBinding,omitempty"`
+	StartArrowhead *string            `json:"startArrowhead,omitempty"`
+	EndArrowhead   *string            `json:"endArrowhead,omitempty"`
+}
+
+// ExcalidrawBinding is both an entry in boundElements ({id, type}) and an
+// arrow end ({elementId, focus, gap})
+type ExcalidrawBinding struct {
+	ID        string  `json:"id,omitempty"`
+	Type      string  `json:"type,omitempty"`
+	ElementID string  `json:"elementId,omitempty"`
+	Focus     float64 `json:"focus"`
+	Gap       float64 `json:"gap"`
+}
+
+// Excalidraw writes doc as an Excalidraw scene
+func Excalidraw(doc *Document) ([]byte, error) {
+	scene := ExcalidrawScene{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "brainstorming-dashboard",
+		AppState: map[string]interface{}{"viewBackgroundColor": hex(background), "gridSize": nil},
+		Files:    map[string]ExcalidrawFile{},
+	}
+	now := time.Now().UnixMilli()
+	base := func(id, kind string, r image.Rectangle) ExcalidrawElement {
+		seed := float64(len(scene.Elements) + 1)
+		return ExcalidrawElement{
+			ID: id, Type: kind,
+			X: float64(r.Min.X), Y: float64(r.Min.Y), Width: float64(r.Dx()), Height: float64(r.Dy()),
+			StrokeColor: hex(ink), BackgroundColor: "transparent", FillStyle: "solid",
+			StrokeWidth: 1, StrokeStyle: "solid", Opacity: 100,
+			GroupIDs: []string{}, Seed: seed, Version: 1, VersionNonce: seed,
+			BoundElements: []ExcalidrawBinding{}, Updated: now,
+		}
+	}
+	textElement := func(id, containerID, text string, r image.Rectangle, align string) ExcalidrawElement {
+		el := base(id, "text", r)
+		el.Text, el.OriginalText = text, text
+		el.FontSize, el.FontFamily, el.LineHeight = 16, 2, 1.25
+		el.TextAlign, el.VerticalAlign = align, "top"
+		el.ContainerID = &containerID
+		return el
+	}
+
+	rects := map[int64]image.Rectangle{}
+	index := map[int64]int{} // card ID -> its element
+	var labels []ExcalidrawElement
+	for _, c := range doc.Cards {
+		r := documentRect(c)
+		rects[c.ID] = r
+		id := fmt.Sprintf("card-%d", c.ID)
+		switch {
+		case c.Kind == "image" && c.Image != nil && len(c.Image.Data) > 0:
+			el := base(id, "image", r)
+			el.StrokeColor = "transparent"
+			el.FileID = fmt.Sprintf("file-%d", c.ID)
+			el.Status = "saved"
+			scene.Files[el.FileID] = ExcalidrawFile{
+				MimeType: c.Image.ContentType,
+				ID:       el.FileID,
+				DataURL:  "data:" + c.Image.ContentType + ";base64," + base64.StdEncoding.EncodeToString(c.Image.Data),
+				Created:  now,
+			}
+			index[c.ID] = len(scene.Elements)
+			scene.Elements = append(scene.Elements, el)
+		case c.Kind == "image":
+			el := base(id, "rectangle", r)
+			el.BackgroundColor = hex(missing)
+			if c.Image != nil && c.Image.URL != "" {
+				link := c.Image.URL
+				el.Link = &link
+				el.CustomData = map[string]interface{}{"kind": "image"}
+			}
+			index[c.ID] = len(scene.Elements)
+			scene.Elements = append(scene.Elements, el)
+		default:
+			el := base(id, "rectangle", r)
+			el.StrokeColor, el.BackgroundColor = hex(border), hex(surface)
+			textID := fmt.Sprintf("text-%d", c.ID)
+			el.BoundElements = append(el.BoundElements, ExcalidrawBinding{ID: textID, Type: "text"})
+			index[c.ID] = len(scene.Elements)
+			scene.Elements = append(scene.Elements, el)
+			labels = append(labels, textElement(textID, id, c.Text, r.Inset(padding), "left"))
+		}
+	}
+	scene.Elements = append(scene.Elements, labels...)
+
+	for n, l := range doc.Links {
+		src, okSrc := index[l.SourceCardID]
+		dst, okDst := index[l.TargetCardID]
+		if !okSrc || !okDst {
+			continue
+		}
+		from, to := linkEnds(rects[l.SourceCardID], rects[l.TargetCardID])
+		id := fmt.Sprintf("link-%d", n+1)
+		el := base(id, "arrow", image.Rectangle{Min: from, Max: from})
+		el.Width, el.Height = math.Abs(float64(to.X-from.X)), math.Abs(float64(to.Y-from.Y))
+		el.StrokeColor, el.StrokeWidth = hex(linkInk), 2
+		switch l.Style {
+		case card.LinkStyleDashed, card.LinkStyleDotted:
+			el.StrokeStyle = l.Style
+		}
+		el.Points = [][2]float64{{0, 0}, {float64(to.X - from.X), float64(to.Y - from.Y)}}
+		el.StartBinding = &ExcalidrawBinding{ElementID: scene.Elements[src].ID, Gap: 4}
+		el.EndBinding = &ExcalidrawBinding{ElementID: scene.Elements[dst].ID, Gap: 4}
+		if l.Directed {
+			arrow := "arrow"
+			el.EndArrowhead = &arrow
+		}
+		scene.Elements[src].BoundElements = append(scene.Elements[src].BoundElements, ExcalidrawBinding{ID: id, Type: "arrow"})
+		scene.Elements[dst].BoundElements = append(scene.Elements[dst].BoundElements, ExcalidrawBinding{ID: id, Type: "arrow"})
+
+		if l.Label != "" {
+			labelID := fmt.Sprintf("label-%d", n+1)
+			el.BoundElements = append(el.BoundElements, ExcalidrawBinding{ID: labelID, Type: "text"})
+			mid := image.Pt((from.X+to.X)/2, (from.Y+to.Y)/2)
+			w := utf8.RuneCountInString(l.Label) * charWidth
+			scene.Elements = append(scene.Elements, el,
+				textElement(labelID, id, l.Label, image.Rect(mid.X-w/2, mid.Y-lineHeight/2, mid.X+w/2, mid.Y+lineHeight/2), "center"))
+			continue
+		}
+		scene.Elements = append(scene.Elements, el)
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(scene); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// documentRect is the box a document card covers, as CardRect. Images
+// without a size take it from the embedded picture.
+func documentRect(c DocumentCard) image.Rectangle {
+	cc := card.Card{Kind: c.Kind, Text: c.Text, PositionX: c.PositionX, PositionY: c.PositionY, Width: c.Width, Height: c.Height}
+	var img image.Image
+	if c.Kind == "image" && c.Width == nil && c.Image != nil && len(c.Image.Data) > 0 {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(c.Image.Data)); err == nil {
+			img = image.Rect(0, 0, cfg.Width, cfg.Height)
+		}
+	}
+	return CardRect(cc, img)
+}
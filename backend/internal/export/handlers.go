@@ -0,0 +1,256 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+type BoardHandler struct {
+	DB    *sql.DB
+	Store storage.Store
+}
+
+// GET /boards/{id}/export.{svg|png|pdf|json|md|opml|excalidraw}
+// perms: owner, edit or read
+func (h *BoardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /boards/{id}/export.{format}
+	if len(parts) != 4 || parts[1] != "boards" || !strings.HasPrefix(parts[3], "export.") {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	Serve(w, r, h.DB, h.Store, boardID, strings.TrimPrefix(parts[3], "export."))
+}
+
+// Serve writes a board export in format for a caller who may read the
+// board. Shared by the board and share-link routes. Pictures of the canvas
+// are "svg", "png" and "pdf"; "json" is the whole board as a Document, and
+// "excalidraw" the same as an Excalidraw scene; "md" and "opml" are the card
+// text as an outline, see Groups.
+//
+// Query params (all optional, pictures only):
+//   - bbox: x,y,width,height in board coordinates to crop to, at most
+//     MaxBBoxSide units on a side
+//   - cards: comma-separated card IDs to include
+//   - scale: PNG pixels per board unit (default 1, 0.1 to 4)
+//   - paginate: PDF only; true tiles the board over A4 pages at full size
+//     instead of fitting it on one page, up to MaxPDFPages pages
+func Serve(w http.ResponseWriter, r *http.Request, db *sql.DB, store storage.Store, boardID int64, format string) {
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, errMsg := parseOptions(r)
+	if errMsg != "" {
+		middleware.JSONError(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	scale, errMsg := parseScale(r.URL.Query().Get("scale"))
+	if errMsg != "" {
+		middleware.JSONError(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	var paginate bool
+	if v := r.URL.Query().Get("paginate"); v != "" {
+		var err error
+		if paginate, err = strconv.ParseBool(v); err != nil {
+			middleware.JSONError(w, "paginate must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var contentType string
+	switch format {
+	case "svg":
+		contentType = "image/svg+xml"
+	case "png":
+		contentType = "image/png"
+	case "pdf":
+		contentType = "application/pdf"
+	case "json", "excalidraw":
+		doc, err := NewDocument(r.Context(), db, store, boardID)
+		if err == ErrDocumentTooLarge {
+			middleware.JSONError(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			log.Printf("export of board %d failed: %v", boardID, err)
+			middleware.JSONError(w, "Failed to load board", http.StatusInternalServerError)
+			return
+		}
+		if format == "json" {
+			attachment(w, boardID, format, "application/json")
+			json.NewEncoder(w).Encode(doc)
+			return
+		}
+		data, err := Excalidraw(doc)
+		if err != nil {
+			log.Printf("export of board %d failed: %v", boardID, err)
+			middleware.JSONError(w, "Failed to render board", http.StatusInternalServerError)
+			return
+		}
+		attachment(w, boardID, format, "application/vnd.excalidraw+json")
+		w.Write(data)
+		return
+	case "md", "opml":
+		data, err := outline(r.Context(), db, store, boardID, format)
+		if err != nil {
+			log.Printf("export of board %d failed: %v", boardID, err)
+			middleware.JSONError(w, "Failed to load board", http.StatusInternalServerError)
+			return
+		}
+		contentType = "text/markdown; charset=utf-8"
+		if format == "opml" {
+			contentType = "text/x-opml; charset=utf-8"
+		}
+		attachment(w, boardID, format, contentType)
+		w.Write(data)
+		return
+	default:
+		middleware.JSONError(w, "Unsupported export format", http.StatusNotFound)
+		return
+	}
+
+	layout, err := Load(r.Context(), db, store, boardID, opts)
+	if err != nil {
+		log.Printf("export of board %d failed: %v", boardID, err)
+		middleware.JSONError(w, "Failed to load board", http.StatusInternalServerError)
+		return
+	}
+
+	var data []byte
+	switch format {
+	case "svg":
+		data = SVG(layout)
+	case "png":
+		data, err = PNG(layout, scale)
+	case "pdf":
+		var meta Meta
+		if meta, err = LoadMeta(db, boardID); err == nil {
+			data, err = PDF(layout, meta, paginate)
+		}
+	}
+	if err == ErrTooManyPages {
+		middleware.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("export of board %d failed: %v", boardID, err)
+		middleware.JSONError(w, "Failed to render board", http.StatusInternalServerError)
+		return
+	}
+
+	attachment(w, boardID, format, contentType)
+	w.Write(data)
+}
+
+// outline writes the board's cards as Markdown or OPML
+func outline(ctx context.Context, db *sql.DB, store storage.Store, boardID int64, format string) ([]byte, error) {
+	meta, err := LoadMeta(db, boardID)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := card.GetCardsByBoard(db, boardID)
+	if err != nil {
+		return nil, err
+	}
+	groups := Groups(cards)
+	if format == "opml" {
+		return OPML(ctx, store, meta, groups)
+	}
+	return Markdown(ctx, store, meta, groups), nil
+}
+
+// attachment sets the headers for a download named board-{id}.{format}
+func attachment(w http.ResponseWriter, boardID int64, format, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="board-%d.%s"`, boardID, format))
+}
+
+func parseOptions(r *http.Request) (Options, string) {
+	var opts Options
+	q := r.URL.Query()
+
+	if v := q.Get("bbox"); v != "" {
+		nums, err := parseInts(v)
+		if err != nil || len(nums) != 4 || nums[2] <= 0 || nums[3] <= 0 {
+			return opts, "bbox must be x,y,width,height"
+		}
+		if nums[2] > MaxBBoxSide || nums[3] > MaxBBoxSide {
+			return opts, fmt.Sprintf("bbox must be at most %d units wide and high", MaxBBoxSide)
+		}
+		if nums[0] < math.MinInt32 || nums[0] > math.MaxInt32 || nums[1] < math.MinInt32 || nums[1] > math.MaxInt32 {
+			return opts, "bbox is out of range"
+		}
+		crop := image.Rect(int(nums[0]), int(nums[1]), int(nums[0]+nums[2]), int(nums[1]+nums[3]))
+		opts.Crop = &crop
+	}
+	if v := q.Get("cards"); v != "" {
+		ids, err := parseInts(v)
+		if err != nil {
+			return opts, "cards must be a comma-separated list of card IDs"
+		}
+		opts.CardIDs = ids
+	}
+	return opts, ""
+}
+
+func parseScale(v string) (float64, string) {
+	if v == "" {
+		return 1, ""
+	}
+	s, err := strconv.ParseFloat(v, 64)
+	if err != nil || s < 0.1 || s > 4 {
+		return 0, "scale must be between 0.1 and 4"
+	}
+	return s, ""
+}
+
+func parseInts(v string) ([]int64, error) {
+	var out []int64
+	for _, p := range strings.Split(v, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// DocumentFormat and DocumentVersion identify a board document. Bump the
+// version when a change would trip up older importers.
+const (
+	DocumentFormat  = "brainstorming-board"
+	DocumentVersion = 1
+)
+
+// MaxDocumentImageBytes caps the images embedded in one document, which is
+// built in memory. Base64 encoded it stays well inside
+// importer.MaxImportBytes, so every export can be imported again.
+const MaxDocumentImageBytes = 64 << 20
+
+var ErrDocumentTooLarge = fmt.Errorf("board has more than %d MB of images to embed", MaxDocumentImageBytes>>20)
+
+// Document is a whole board as one JSON file, for backups and for moving
+// boards between environments
+type Document struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Board      DocumentBoard  `json:"board"`
+	Cards      []DocumentCard `json:"cards"`
+	Links      []DocumentLink `json:"links"`
+}
+
+type DocumentBoard struct {
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DocumentCard is a card. ID only ties links to cards within the document;
+// imports get new IDs.
+type DocumentCard struct {
+	ID        int64          `json:"id"`
+	Kind      string         `json:"kind"` // "text" | "image"
+	Text      string         `json:"text,omitempty"`
+	Image     *DocumentImage `json:"image,omitempty"`
+	PositionX float64        `json:"position_x"`
+	PositionY float64        `json:"position_y"`
+	Width     *float64       `json:"width,omitempty"`
+	Height    *float64       `json:"height,omitempty"`
+}
+
+// DocumentImage carries an image card's picture: the bytes for images kept
+// in storage, or just the URL for images hosted elsewhere. Data is base64 in
+// the JSON.
+type DocumentImage struct {
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+type DocumentLink struct {
+	SourceCardID int64  `json:"source_card_id"`
+	TargetCardID int64  `json:"target_card_id"`
+	Label        string `json:"label,omitempty"`
+	Style        string `json:"style"`
+	Directed     bool   `json:"directed"`
+}
+
+// NewDocument reads a board into a Document, with the original of every
+// stored image embedded. Images that can no longer be read are left out and
+// their cards keep no picture. Returns ErrDocumentTooLarge once the images
+// pass MaxDocumentImageBytes.
+func NewDocument(ctx context.Context, db *sql.DB, store storage.Store, boardID int64) (*Document, error) {
+	doc := &Document{Format: DocumentFormat, Version: DocumentVersion, ExportedAt: time.Now().UTC()}
+	err := db.QueryRow("SELECT title, created_at FROM boards WHERE id = ?", boardID).Scan(&doc.Board.Title, &doc.Board.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := card.GetCardsByBoard(db, boardID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := card.GetLinksByBoard(db, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Cards = make([]DocumentCard, 0, len(cards))
+	var embedded int
+	for _, c := range cards {
+		dc := DocumentCard{
+			ID:        c.ID,
+			Kind:      c.Kind,
+			Text:      c.Text,
+			PositionX: c.PositionX,
+			PositionY: c.PositionY,
+			Width:     c.Width,
+			Height:    c.Height,
+		}
+		if c.Kind == "image" && c.ImageURL != "" {
+			dc.Image, err = readDocumentImage(ctx, store, c.ImageURL, MaxDocumentImageBytes-embedded)
+			if err != nil {
+				return nil, err
+			}
+			if dc.Image != nil {
+				embedded += len(dc.Image.Data)
+			}
+		}
+		doc.Cards = append(doc.Cards, dc)
+	}
+	doc.Links = make([]DocumentLink, 0, len(links))
+	for _, l := range links {
+		doc.Links = append(doc.Links, DocumentLink{
+			SourceCardID: l.SourceCardID,
+			TargetCardID: l.TargetCardID,
+			Label:        l.Label,
+			Style:        l.Style,
+			Directed:     l.Directed,
+		})
+	}
+	return doc, nil
+}
+
+// readDocumentImage loads a stored image to embed, or nil if it cannot be
+// read or is too big to import again. Fails with ErrDocumentTooLarge when it
+// would take more than budget bytes.
+func readDocumentImage(ctx context.Context, store storage.Store, ref string, budget int) (*DocumentImage, error) {
+	if !storage.IsKey(ref) {
+		return &DocumentImage{URL: ref}, nil
+	}
+	if store == nil {
+		return nil, nil
+	}
+	obj, err := store.Open(ctx, ref)
+	if err != nil {
+		return nil, nil
+	}
+	defer obj.Body.Close()
+	limit := min(card.MaxImageBytes, budget)
+	data, err := io.ReadAll(io.LimitReader(obj.Body, int64(limit)+1))
+	if err != nil {
+		return nil, nil
+	}
+	if len(data) > limit {
+		if limit == budget {
+			return nil, ErrDocumentTooLarge
+		}
+		return nil, nil
+	}
+	return &DocumentImage{ContentType: obj.ContentType, Data: data}, nil
+}
@@ -0,0 +1,250 @@
+// Package export renders boards into files people can take elsewhere:
+// SVG, PNG and PDF pictures of the canvas, laid out from the cards' stored
+// coordinates and sizes, Markdown and OPML outlines of the card text, and
+// JSON and Excalidraw documents. Package importer reads the documents and
+// scenes back in.
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"image"
+	_ "image/gif" // registers the decoders stored images may need
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+	_ "golang.org/x/image/webp"
+)
+
+// Card sizes the canvas uses when a card has none of its own
+const (
+	TextCardWidth  = 360
+	textCardHeight = 96
+	maxTextHeight  = 284 // textarea cap plus padding
+	maxImageWidth  = 640
+	imageFallback  = 240
+)
+
+const (
+	margin     = 40 // board pixels around the outermost cards
+	padding    = 12 // inside text cards
+	lineHeight = 18
+	charWidth  = 7 // average glyph width used for wrapping
+)
+
+// MaxBBoxSide caps the width and height of a requested crop, in board units
+const MaxBBoxSide = 100_000
+
+// Options narrow down what is exported. The zero value is the whole board.
+type Options struct {
+	Crop        *image.Rectangle // board coordinates to show; default fits the cards
+	CardIDs     []int64          // only these cards (and links between them)
+	SmallImages bool             // always use the smallest image rendition
+}
+
+// Item is a card with the box it takes up on the board
+type Item struct {
+	Card        card.Card
+	Rect        image.Rectangle
+	Image       image.Image // decoded picture for image cards, nil if unavailable
+	Data        []byte      // the stored bytes behind Image
+	ContentType string
+}
+
+// Layout is everything needed to draw part of a board
+type Layout struct {
+	Bounds image.Rectangle // the area drawn, in board coordinates
+	Items  []Item
+	Links  []card.Link
+}
+
+// Load reads a board's cards and links and lays them out
+func Load(ctx context.Context, db *sql.DB, store storage.Store, boardID int64, opts Options) (*Layout, error) {
+	cards, err := card.GetCardsByBoard(db, boardID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := card.GetLinksByBoard(db, boardID)
+	if err != nil {
+		return nil, err
+	}
+	return NewLayout(ctx, store, cards, links, opts), nil
+}
+
+// NewLayout places cards at their stored positions. Images are read from
+// store in the smallest rendition that still looks sharp at their size;
+// external image URLs are not fetched and are drawn as blank boxes.
+func NewLayout(ctx context.Context, store storage.Store, cards []card.Card, links []card.Link, opts Options) *Layout {
+	var only map[int64]bool
+	if opts.CardIDs != nil {
+		only = make(map[int64]bool, len(opts.CardIDs))
+		for _, id := range opts.CardIDs {
+			only[id] = true
+		}
+	}
+
+	l := &Layout{}
+	kept := map[int64]bool{}
+	var bounds image.Rectangle
+	for _, c := range cards {
+		if only != nil && !only[c.ID] {
+			continue
+		}
+		it := Item{Card: c, Rect: CardRect(c, nil)}
+		if c.Kind == "image" {
+			it.loadImage(ctx, store, opts.SmallImages)
+			it.Rect = CardRect(c, it.Image)
+		}
+		l.Items = append(l.Items, it)
+		kept[c.ID] = true
+		bounds = bounds.Union(it.Rect)
+	}
+	for _, link := range links {
+		if kept[link.SourceCardID] && kept[link.TargetCardID] {
+			l.Links = append(l.Links, link)
+		}
+	}
+
+	switch {
+	case opts.Crop != nil:
+		l.Bounds = *opts.Crop
+	case bounds.Empty():
+		l.Bounds = image.Rect(0, 0, 2*margin, 2*margin)
+	default:
+		l.Bounds = bounds.Inset(-margin)
+	}
+	return l
+}
+
+// CardRect is the box a card covers on the board. Image cards without a
+// size take their picture's natural size, capped in width like the canvas.
+func CardRect(c card.Card, img image.Image) image.Rectangle {
+	x, y := int(c.PositionX), int(c.PositionY)
+	if c.Width != nil && c.Height != nil && *c.Width > 0 && *c.Height > 0 {
+		return image.Rect(x, y, x+int(*c.Width), y+int(*c.Height))
+	}
+	if c.Kind != "image" {
+		h := textCardHeight
+		if lines := len(Wrap(c.Text, (TextCardWidth-2*padding)/charWidth)); lines > 3 {
+			h = int(math.Min(float64(lines*lineHeight+2*padding), maxTextHeight))
+		}
+		return image.Rect(x, y, x+TextCardWidth, y+h)
+	}
+	if img == nil {
+		return image.Rect(x, y, x+imageFallback, y+imageFallback)
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w > maxImageWidth {
+		h = h * maxImageWidth / w
+		w = maxImageWidth
+	}
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// loadImage reads the card's picture. Without a stored size the original is
+// needed for its natural dimensions; otherwise the smallest variant at least
+// as large as the card is enough.
+func (it *Item) loadImage(ctx context.Context, store storage.Store, small bool) {
+	c := it.Card
+	key := c.ImageURL
+	if c.Width != nil && c.Height != nil {
+		side := int(math.Max(*c.Width, *c.Height))
+		for _, size := range []int{256, 1024} {
+			v, ok := c.Variants[strconv.Itoa(size)]
+			if ok && (small || side <= size) {
+				key = v
+				break
+			}
+		}
+	}
+	if store == nil || !storage.IsKey(key) {
+		return
+	}
+	obj, err := store.Open(ctx, key)
+	if err != nil {
+		return
+	}
+	defer obj.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(obj.Body, card.MaxImageBytes))
+	if err != nil {
+		return
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	it.Image, it.Data, it.ContentType = img, data, "image/"+format
+}
+
+// linkEnds is where a link between two cards meets their edges, on the line
+// between their centres
+func linkEnds(from, to image.Rectangle) (image.Point, image.Point) {
+	a, b := center(from), center(to)
+	return edge(from, a, b), edge(to, b, a)
+}
+
+// edge moves c (r's centre) towards target until it reaches r's border
+func edge(r image.Rectangle, c, target image.Point) image.Point {
+	dx, dy := float64(target.X-c.X), float64(target.Y-c.Y)
+	if dx == 0 && dy == 0 {
+		return c
+	}
+	t := math.Inf(1)
+	if dx != 0 {
+		t = math.Min(t, float64(r.Dx())/2/math.Abs(dx))
+	}
+	if dy != 0 {
+		t = math.Min(t, float64(r.Dy())/2/math.Abs(dy))
+	}
+	t = math.Min(t, 1)
+	return image.Pt(c.X+int(dx*t), c.Y+int(dy*t))
+}
+
+func center(r image.Rectangle) image.Point {
+	return image.Pt((r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2)
+}
+
+// Lines is a text card's text wrapped to its width
+func (it Item) Lines() []string {
+	return Wrap(it.Card.Text, (it.Rect.Dx()-2*padding)/charWidth)
+}
+
+// Wrap breaks text into lines of at most width characters, on spaces where
+// it can
+func Wrap(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		cur := ""
+		for _, w := range strings.Fields(para) {
+			for len(w) > width {
+				if cur != "" {
+					lines = append(lines, cur)
+					cur = ""
+				}
+				lines = append(lines, w[:width])
+				w = w[width:]
+			}
+			switch {
+			case cur == "":
+				cur = w
+			case len(cur)+1+len(w) <= width:
+				cur += " " + w
+			default:
+				lines = append(lines, cur)
+				cur = w
+			}
+		}
+		lines = append(lines, cur)
+	}
+	return lines
+}
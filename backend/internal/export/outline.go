@@ -0,0 +1,233 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// clusterGap is how far apart, in board units, cards can sit and still be
+// read as one group
+const clusterGap = 80
+
+// Group is a cluster of nearby cards in reading order. The first card heads
+// the group and the rest sit under it.
+type Group []card.Card
+
+// Groups clusters cards that are within clusterGap of each other and orders
+// the clusters, and the cards inside them, top-to-bottom and left-to-right.
+// Text cards with no text are left out.
+func Groups(cards []card.Card) []Group {
+	var kept []card.Card
+	var rects []image.Rectangle
+	for _, c := range cards {
+		if c.Kind != "image" && strings.TrimSpace(c.Text) == "" {
+			continue
+		}
+		kept = append(kept, c)
+		rects = append(rects, CardRect(c, nil))
+	}
+
+	// Union nearby cards
+	parent := make([]int, len(kept))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range rects {
+		near := rects[i].Inset(-clusterGap / 2)
+		for j := i + 1; j < len(rects); j++ {
+			if near.Overlaps(rects[j].Inset(-clusterGap / 2)) {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	var roots []int
+	for i := range kept {
+		r := root(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], i)
+	}
+
+	bounds := make([]image.Rectangle, len(roots))
+	for n, r := range roots {
+		for _, i := range members[r] {
+			bounds[n] = bounds[n].Union(rects[i])
+		}
+	}
+	var groups []Group
+	for _, n := range readingOrder(bounds) {
+		idx := members[roots[n]]
+		sub := make([]image.Rectangle, len(idx))
+		for k, i := range idx {
+			sub[k] = rects[i]
+		}
+		var g Group
+		for _, k := range readingOrder(sub) {
+			g = append(g, kept[idx[k]])
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// readingOrder sorts boxes into rows, where a box starting above the bottom
+// of the row so far joins it, and each row left to right. Returns indexes
+// into rects.
+func readingOrder(rects []image.Rectangle) []int {
+	order := make([]int, len(rects))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, rb := rects[order[a]], rects[order[b]]
+		if ra.Min.Y != rb.Min.Y {
+			return ra.Min.Y < rb.Min.Y
+		}
+		return ra.Min.X < rb.Min.X
+	})
+
+	for start := 0; start < len(order); {
+		bottom := rects[order[start]].Max.Y
+		end := start + 1
+		for end < len(order) && rects[order[end]].Min.Y < bottom {
+			if y := rects[order[end]].Max.Y; y > bottom {
+				bottom = y
+			}
+			end++
+		}
+		row := order[start:end]
+		sort.SliceStable(row, func(a, b int) bool {
+			return rects[row[a]].Min.X < rects[row[b]].Min.X
+		})
+		start = end
+	}
+	return order
+}
+
+// imageLink is a URL for an image card, or "" if there is none. Stored
+// images get the same short-lived signed URLs the app hands out, so links in
+// an exported outline stop working after storage.URLTTL.
+func imageLink(ctx context.Context, store storage.Store, ref string) string {
+	ref = storage.NormalizeWith(store, ref)
+	if !storage.IsKey(ref) {
+		return ref
+	}
+	if store == nil {
+		return ""
+	}
+	url, err := store.SignedURL(ctx, ref, storage.URLTTL)
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// Markdown writes the groups as a bulleted list under the board title: each
+// group's first card at the top level and the rest nested under it. Image
+// cards become image links.
+func Markdown(ctx context.Context, store storage.Store, meta Meta, groups []Group) []byte {
+	var b bytes.Buffer
+	title := meta.Title
+	if strings.TrimSpace(title) == "" {
+		title = "Untitled board"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", oneLine(title))
+	for _, g := range groups {
+		for i, c := range g {
+			indent := ""
+			if i > 0 {
+				indent = "  "
+			}
+			b.WriteString(indent + "- ")
+			if c.Kind == "image" {
+				fmt.Fprintf(&b, "![image](%s)\n", imageLink(ctx, store, c.ImageURL))
+				continue
+			}
+			lines := strings.Split(strings.TrimSpace(c.Text), "\n")
+			for n, line := range lines {
+				if n > 0 {
+					b.WriteString(indent + "  ")
+				}
+				b.WriteString(strings.TrimRight(line, " \t\r") + "\n")
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Head    opmlHead      `xml:"head"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Note     string        `xml:"_note,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	URL      string        `xml:"url,attr,omitempty"`
+	Children []opmlOutline `xml:"outline"`
+}
+
+// OPML writes the same outline as Markdown for outliner tools. A card's
+// first line is the item's text and any further lines go in its note.
+func OPML(ctx context.Context, store storage.Store, meta Meta, groups []Group) ([]byte, error) {
+	doc := opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       meta.Title,
+			DateCreated: meta.Exported.UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, g := range groups {
+		top := opmlItem(ctx, store, g[0])
+		for _, c := range g[1:] {
+			top.Children = append(top.Children, opmlItem(ctx, store, c))
+		}
+		doc.Body = append(doc.Body, top)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+func opmlItem(ctx context.Context, store storage.Store, c card.Card) opmlOutline {
+	if c.Kind == "image" {
+		return opmlOutline{Text: "Image", Type: "link", URL: imageLink(ctx, store, c.ImageURL)}
+	}
+	text := strings.TrimSpace(c.Text)
+	first, rest, _ := strings.Cut(text, "\n")
+	return opmlOutline{Text: strings.TrimSpace(first), Note: strings.TrimSpace(rest)}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
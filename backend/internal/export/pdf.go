@@ -0,0 +1,249 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Meta is printed in a PDF's header and heads outlines. It holds nothing
+// about the owner, as share-link guests can export too.
+type Meta struct {
+	Title    string
+	Exported time.Time
+}
+
+// LoadMeta reads the header details for a board
+func LoadMeta(db *sql.DB, boardID int64) (Meta, error) {
+	m := Meta{Exported: time.Now()}
+	err := db.QueryRow("SELECT title FROM boards WHERE id = ?", boardID).Scan(&m.Title)
+	return m, err
+}
+
+// PDF page geometry, in points
+const (
+	pxToPt       = 0.75 // one board unit is one CSS pixel
+	pageMargin   = 36
+	headerHeight = 40
+	maxPageSide  = 14400 // the largest page PDF viewers accept
+)
+
+// A4 landscape, used when the board is split across pages
+var a4 = gofpdf.SizeType{Wd: 842, Ht: 595}
+
+// MaxPDFPages caps a paginated export
+const MaxPDFPages = 100
+
+var ErrTooManyPages = fmt.Errorf("board needs more than %d pages; export a smaller bbox or a single page", MaxPDFPages)
+
+// pdfFont is the same Go font the PNG export uses, embedded so any UTF-8
+// card text comes out right
+const pdfFont = "go"
+
+// PDF draws l with a header of meta. With paged set the board is tiled over
+// A4 pages at full size; otherwise it is shrunk onto one page as needed.
+// Card text stays real text, not pixels.
+func PDF(l *Layout, meta Meta, paged bool) ([]byte, error) {
+	bounds := l.Bounds
+	scale := pxToPt
+	var page gofpdf.SizeType
+	if paged {
+		page = a4
+	} else {
+		longest := math.Max(float64(bounds.Dx()), float64(bounds.Dy())) * scale
+		if limit := float64(maxPageSide - 2*pageMargin - headerHeight); longest > limit {
+			scale *= limit / longest
+		}
+		page = gofpdf.SizeType{
+			Wd: math.Max(float64(bounds.Dx())*scale+2*pageMargin, 300),
+			Ht: float64(bounds.Dy())*scale + 2*pageMargin + headerHeight,
+		}
+	}
+
+	// Board area covered by each page
+	contentW := page.Wd - 2*pageMargin
+	contentH := page.Ht - 2*pageMargin - headerHeight
+	tileW, tileH := contentW/scale, contentH/scale
+	cols, rows := 1.0, 1.0
+	if paged {
+		cols = math.Max(1, math.Ceil(float64(bounds.Dx())/tileW))
+		rows = math.Max(1, math.Ceil(float64(bounds.Dy())/tileH))
+		if cols*rows > MaxPDFPages {
+			return nil, ErrTooManyPages
+		}
+	}
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "pt", Size: page})
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(meta.Title, true)
+	pdf.SetCreationDate(meta.Exported)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+
+	images := registerImages(pdf, l)
+	for row := 0; row < int(rows); row++ {
+		for col := 0; col < int(cols); col++ {
+			pdf.AddPage()
+			n := row*int(cols) + col + 1
+			writeHeader(pdf, meta, page, n, int(cols*rows))
+
+			origin := gofpdf.PointType{
+				X: float64(bounds.Min.X) + float64(col)*tileW,
+				Y: float64(bounds.Min.Y) + float64(row)*tileH,
+			}
+			pdf.ClipRect(pageMargin, pageMargin+headerHeight, contentW, contentH, false)
+			pdf.SetFillColor(int(background.R), int(background.G), int(background.B))
+			pdf.Rect(pageMargin, pageMargin+headerHeight, contentW, contentH, "F")
+			tile := image.Rect(
+				int(math.Floor(origin.X)), int(math.Floor(origin.Y)),
+				int(math.Ceil(origin.X+tileW)), int(math.Ceil(origin.Y+tileH)),
+			)
+			drawPDFBoard(pdf, l, images, origin, tile, scale)
+			pdf.ClipEnd()
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeHeader(pdf *gofpdf.Fpdf, meta Meta, page gofpdf.SizeType, n, pages int) {
+	pdf.SetTextColor(int(ink.R), int(ink.G), int(ink.B))
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.Text(pageMargin, pageMargin+14, meta.Title)
+
+	pdf.SetFont(pdfFont, "", 9)
+	line := "Exported " + meta.Exported.Format("2 Jan 2006 15:04 MST")
+	if pages > 1 {
+		line += fmt.Sprintf("  –  page %d of %d", n, pages)
+	}
+	pdf.Text(pageMargin, pageMargin+28, line)
+
+	pdf.SetDrawColor(int(border.R), int(border.G), int(border.B))
+	pdf.SetLineWidth(0.5)
+	pdf.Line(pageMargin, pageMargin+headerHeight-6, page.Wd-pageMargin, pageMargin+headerHeight-6)
+}
+
+// registerImages adds each image card's picture to the document once and
+// returns the names to draw them by. JPEGs go in as they are; everything
+// else is re-encoded as PNG, which gofpdf reads reliably.
+func registerImages(pdf *gofpdf.Fpdf, l *Layout) map[int64]string {
+	names := map[int64]string{}
+	for _, it := range l.Items {
+		if it.Image == nil {
+			continue
+		}
+		name := fmt.Sprintf("card-%d", it.Card.ID)
+		data, kind := it.Data, "JPG"
+		if it.ContentType != "image/jpeg" {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, it.Image); err != nil {
+				continue
+			}
+			data, kind = buf.Bytes(), "PNG"
+		}
+		pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: kind}, bytes.NewReader(data))
+		if pdf.Ok() {
+			names[it.Card.ID] = name
+		} else {
+			pdf.ClearError() // draw a blank box instead
+		}
+	}
+	return names
+}
+
+// drawPDFBoard draws the cards touching tile relative to origin (board
+// coordinates at the content area's top left); the page clip hides what
+// falls outside
+func drawPDFBoard(pdf *gofpdf.Fpdf, l *Layout, images map[int64]string, origin gofpdf.PointType, tile image.Rectangle, scale float64) {
+	at := func(p image.Point) (float64, float64) {
+		return pageMargin + (float64(p.X)-origin.X)*scale, pageMargin + headerHeight + (float64(p.Y)-origin.Y)*scale
+	}
+
+	rects := make(map[int64]image.Rectangle, len(l.Items))
+	for _, it := range l.Items {
+		rects[it.Card.ID] = it.Rect
+	}
+	pdf.SetDrawColor(int(linkInk.R), int(linkInk.G), int(linkInk.B))
+	pdf.SetFillColor(int(linkInk.R), int(linkInk.G), int(linkInk.B))
+	pdf.SetLineWidth(2 * scale)
+	for _, link := range l.Links {
+		from, to := linkEnds(rects[link.SourceCardID], rects[link.TargetCardID])
+		x1, y1 := at(from)
+		x2, y2 := at(to)
+		switch link.Style {
+		case card.LinkStyleDashed:
+			pdf.SetDashPattern([]float64{8 * scale, 4 * scale}, 0)
+		case card.LinkStyleDotted:
+			pdf.SetDashPattern([]float64{2 * scale, 4 * scale}, 0)
+		}
+		pdf.Line(x1, y1, x2, y2)
+		pdf.SetDashPattern([]float64{}, 0)
+		if link.Directed {
+			arrowHead(pdf, x1, y1, x2, y2, 8*scale)
+		}
+		if link.Label != "" {
+			pdf.SetFont(pdfFont, "", 12*scale)
+			pdf.SetTextColor(int(ink.R), int(ink.G), int(ink.B))
+			w := pdf.GetStringWidth(link.Label)
+			pdf.Text((x1+x2)/2-w/2, (y1+y2)/2-4*scale, link.Label)
+		}
+	}
+
+	for _, it := range l.Items {
+		if !it.Rect.Overlaps(tile) {
+			continue
+		}
+		x, y := at(it.Rect.Min)
+		w, h := float64(it.Rect.Dx())*scale, float64(it.Rect.Dy())*scale
+		if it.Card.Kind == "image" {
+			if name, ok := images[it.Card.ID]; ok {
+				pdf.ImageOptions(name, x, y, w, h, false, gofpdf.ImageOptions{}, 0, "")
+			} else {
+				pdf.SetFillColor(int(missing.R), int(missing.G), int(missing.B))
+				pdf.Rect(x, y, w, h, "F")
+			}
+			continue
+		}
+
+		pdf.SetFillColor(int(surface.R), int(surface.G), int(surface.B))
+		pdf.SetDrawColor(int(border.R), int(border.G), int(border.B))
+		pdf.SetLineWidth(0.75 * scale)
+		pdf.Rect(x, y, w, h, "FD")
+
+		// Selectable text, clipped to the card like the canvas does
+		pdf.SetFont(pdfFont, "", fontSize*scale)
+		pdf.SetTextColor(int(ink.R), int(ink.G), int(ink.B))
+		pdf.ClipRect(x, y, w, h, false)
+		for n, line := range it.Lines() {
+			top := y + (padding+float64(n*lineHeight))*scale
+			if top+lineHeight*scale > y+h {
+				break
+			}
+			pdf.Text(x+padding*scale, top+fontSize*scale, line)
+		}
+		pdf.ClipEnd()
+	}
+}
+
+// arrowHead draws a filled arrow at (x2, y2) pointing away from (x1, y1)
+func arrowHead(pdf *gofpdf.Fpdf, x1, y1, x2, y2, size float64) {
+	angle := math.Atan2(y2-y1, x2-x1)
+	pdf.Polygon([]gofpdf.PointType{
+		{X: x2, Y: y2},
+		{X: x2 - size*math.Cos(angle-math.Pi/7), Y: y2 - size*math.Sin(angle-math.Pi/7)},
+		{X: x2 - size*math.Cos(angle+math.Pi/7), Y: y2 - size*math.Sin(angle+math.Pi/7)},
+	}, "F")
+}
@@ -0,0 +1,214 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Limits on a drawn canvas; larger requests are drawn at a smaller scale
+const (
+	MaxPixels    = 40_000_000
+	MaxDimension = 16384 // pixels on either side
+)
+
+// Card text size in board units, and the smallest size still drawn as text
+const (
+	fontSize    = 13
+	minFontSize = 6
+)
+
+var (
+	background = color.RGBA{0xf1, 0xf5, 0xf9, 0xff}
+	surface    = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	border     = color.RGBA{0xcb, 0xd5, 0xe1, 0xff}
+	ink        = color.RGBA{0x1e, 0x29, 0x3b, 0xff}
+	linkInk    = color.RGBA{0x94, 0xa3, 0xb8, 0xff}
+	missing    = color.RGBA{0xe2, 0xe8, 0xf0, 0xff}
+)
+
+// FitScale lowers scale as far as needed to keep l within MaxDimension on
+// either side and MaxPixels in all
+func FitScale(l *Layout, scale float64) float64 {
+	w, h := float64(l.Bounds.Dx())*scale, float64(l.Bounds.Dy())*scale
+	if longest := math.Max(w, h); longest > MaxDimension {
+		scale *= MaxDimension / longest
+		w, h = w*MaxDimension/longest, h*MaxDimension/longest
+	}
+	if area := math.Max(1, w) * math.Max(1, h); area > MaxPixels {
+		scale *= math.Sqrt(MaxPixels / area)
+	}
+	return scale
+}
+
+// PNG draws l at scale (1 = one pixel per board unit)
+func PNG(l *Layout, scale float64) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Draw(l, scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Draw rasterises l at scale, lowered by FitScale when the canvas would be
+// too big. Text too small to read is drawn as grey bars.
+func Draw(l *Layout, scale float64) *image.RGBA {
+	scale = FitScale(l, scale)
+	w := canvasSide(float64(l.Bounds.Dx()) * scale)
+	h := canvasSide(float64(l.Bounds.Dy()) * scale)
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill(canvas, canvas.Bounds(), background)
+
+	toCanvas := func(r image.Rectangle) image.Rectangle {
+		r = r.Sub(l.Bounds.Min)
+		return image.Rect(
+			int(float64(r.Min.X)*scale), int(float64(r.Min.Y)*scale),
+			int(float64(r.Max.X)*scale), int(float64(r.Max.Y)*scale),
+		)
+	}
+
+	rects := make(map[int64]image.Rectangle, len(l.Items))
+	for _, it := range l.Items {
+		rects[it.Card.ID] = toCanvas(it.Rect)
+	}
+	for _, link := range l.Links {
+		from, to := linkEnds(rects[link.SourceCardID], rects[link.TargetCardID])
+		drawLine(canvas, from, to, link.Style, int(math.Max(1, 2*scale)))
+	}
+
+	face := textFace(scale)
+	for _, it := range l.Items {
+		r := toCanvas(it.Rect)
+		if it.Card.Kind == "image" {
+			drawImage(canvas, r, it.Image)
+		} else {
+			drawText(canvas, r, it.Lines(), scale, face)
+		}
+	}
+	return canvas
+}
+
+// canvasSide rounds a canvas side to a whole number of pixels within
+// [1, MaxDimension]
+func canvasSide(px float64) int {
+	return int(math.Min(MaxDimension, math.Max(1, px)))
+}
+
+var (
+	fontOnce sync.Once
+	regular  *opentype.Font
+)
+
+// textFace is the card font at scale, or nil when it would be too small to
+// read
+func textFace(scale float64) font.Face {
+	size := fontSize * scale
+	if size < minFontSize {
+		return nil
+	}
+	fontOnce.Do(func() {
+		var err error
+		if regular, err = opentype.Parse(goregular.TTF); err != nil {
+			log.Printf("WARN: failed to load export font: %v", err)
+		}
+	})
+	if regular == nil {
+		return nil
+	}
+	face, err := opentype.NewFace(regular, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	return face
+}
+
+func drawImage(dst *image.RGBA, r image.Rectangle, img image.Image) {
+	if r.Empty() {
+		return
+	}
+	if img == nil {
+		fill(dst, r, missing)
+		return
+	}
+	scaled := imaging.Resize(img, r.Dx(), r.Dy(), imaging.Linear)
+	draw.Draw(dst, r, scaled, image.Point{}, draw.Over)
+}
+
+func drawText(dst *image.RGBA, r image.Rectangle, lines []string, scale float64, face font.Face) {
+	fill(dst, r, border)
+	fill(dst, r.Inset(1), surface)
+
+	inner := r.Inset(int(padding * scale))
+	if inner.Empty() {
+		return
+	}
+	step := lineHeight * scale
+
+	// Too small to read: draw grey bars where the lines would be
+	if face == nil {
+		for i, l := range lines {
+			y := inner.Min.Y + int(float64(i)*step)
+			if y+int(step/2) > inner.Max.Y {
+				break
+			}
+			w := int(math.Min(float64(len(l)*charWidth)*scale, float64(inner.Dx())))
+			fill(dst, image.Rect(inner.Min.X, y, inner.Min.X+w, y+int(math.Max(1, step/2))), border)
+		}
+		return
+	}
+
+	d := &font.Drawer{
+		Dst:  dst.SubImage(inner).(*image.RGBA),
+		Src:  image.NewUniform(ink),
+		Face: face,
+	}
+	ascent := face.Metrics().Ascent
+	for i, l := range lines {
+		top := inner.Min.Y + int(float64(i)*step)
+		if top > inner.Max.Y {
+			break
+		}
+		d.Dot = fixed.Point26_6{X: fixed.I(inner.Min.X), Y: fixed.I(top) + ascent}
+		d.DrawString(l)
+	}
+}
+
+func fill(dst *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(dst, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// drawLine draws a straight link in its style
+func drawLine(dst *image.RGBA, from, to image.Point, style string, thickness int) {
+	dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+	steps := int(math.Max(math.Abs(dx), math.Abs(dy)))
+	if steps == 0 {
+		return
+	}
+	on, period := 1, 1
+	switch style {
+	case card.LinkStyleDashed:
+		on, period = 8*thickness, 12*thickness
+	case card.LinkStyleDotted:
+		on, period = thickness, 3*thickness
+	}
+	for i := 0; i <= steps; i++ {
+		if i%period >= on {
+			continue
+		}
+		x := from.X + int(dx*float64(i)/float64(steps))
+		y := from.Y + int(dy*float64(i)/float64(steps))
+		fill(dst, image.Rect(x-thickness/2, y-thickness/2, x+thickness-thickness/2, y+thickness-thickness/2), linkInk)
+	}
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+)
+
+// SVG draws l as a standalone SVG document. Images are embedded as data
+// URIs so the file keeps working after signed URLs expire.
+func SVG(l *Layout) []byte {
+	var b bytes.Buffer
+	bounds := l.Bounds
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%d %d %d %d">`+"\n",
+		bounds.Dx(), bounds.Dy(), bounds.Min.X, bounds.Min.Y, bounds.Dx(), bounds.Dy())
+	fmt.Fprintf(&b, `<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="%s"/></marker></defs>`+"\n", hex(linkInk))
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+		bounds.Min.X, bounds.Min.Y, bounds.Dx(), bounds.Dy(), hex(background))
+
+	index := make(map[int64]Item, len(l.Items))
+	for _, it := range l.Items {
+		index[it.Card.ID] = it
+	}
+	for _, link := range l.Links {
+		from, to := linkEnds(index[link.SourceCardID].Rect, index[link.TargetCardID].Rect)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"%s%s/>`+"\n",
+			from.X, from.Y, to.X, to.Y, hex(linkInk), dashArray(link.Style), arrow(link.Directed))
+		if link.Label != "" {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="%s" font-size="12" fill="%s" text-anchor="middle">%s</text>`+"\n",
+				(from.X+to.X)/2, (from.Y+to.Y)/2-4, fontFamily, hex(ink), escape(link.Label))
+		}
+	}
+
+	for i, it := range l.Items {
+		r := it.Rect
+		if it.Card.Kind == "image" {
+			if it.Data == nil {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+					r.Min.X, r.Min.Y, r.Dx(), r.Dy(), hex(missing))
+				continue
+			}
+			fmt.Fprintf(&b, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="none" href="data:%s;base64,%s"/>`+"\n",
+				r.Min.X, r.Min.Y, r.Dx(), r.Dy(), it.ContentType, base64.StdEncoding.EncodeToString(it.Data))
+			continue
+		}
+
+		// Text cards clip their text to the box, like the canvas does
+		fmt.Fprintf(&b, `<clipPath id="card-%d"><rect x="%d" y="%d" width="%d" height="%d"/></clipPath>`+"\n",
+			i, r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="%s"/>`+"\n",
+			r.Min.X, r.Min.Y, r.Dx(), r.Dy(), hex(surface), hex(border))
+		fmt.Fprintf(&b, `<text clip-path="url(#card-%d)" font-family="%s" font-size="%d" fill="%s">`, i, fontFamily, fontSize, hex(ink))
+		for n, line := range it.Lines() {
+			top := r.Min.Y + padding + n*lineHeight
+			if top+lineHeight > r.Max.Y {
+				break
+			}
+			fmt.Fprintf(&b, `<tspan x="%d" y="%d">%s</tspan>`, r.Min.X+padding, top+fontSize, escape(line))
+		}
+		b.WriteString("</text>\n")
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+const fontFamily = "ui-sans-serif, system-ui, sans-serif"
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func dashArray(style string) string {
+	switch style {
+	case card.LinkStyleDashed:
+		return ` stroke-dasharray="8 4"`
+	case card.LinkStyleDotted:
+		return ` stroke-dasharray="2 4"`
+	}
+	return ""
+}
+
+func arrow(directed bool) string {
+	if directed {
+		return ` marker-end="url(#arrow)"`
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+// Package gc removes stored images and thumbnails nothing refers to any more:
+// uploads that never became a card, and images whose cards were deleted once
+// no revision or snapshot can bring them back. It is the only place stored
+// images are deleted, and it gives their bytes back to the uploaders' quota.
+//
+// Retention: an image stays while a card or board uses it, for Grace after
+// it was last uploaded, while a card revision or automatic snapshot from the
+// last History mentions it, and for as long as a manual snapshot does.
+// Reverting to older history, or restoring an older automatic snapshot,
+// brings cards back without their pictures. Both periods are set by the
+// API's IMAGE_GC_GRACE and IMAGE_GC_HISTORY, or cmd/gc's -grace and -history.
+package gc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// DefaultGrace keeps fresh objects safe from a sweep, so an upload the
+// client has not turned into a card yet is never collected
+const DefaultGrace = 24 * time.Hour
+
+// DefaultHistory is how long card history and automatic snapshots keep the
+// images they mention. Manual snapshots keep theirs for as long as they exist.
+const DefaultHistory = 30 * 24 * time.Hour
+
+// Prefixes swept; other keys in the store are never touched
+var Prefixes = []string{"images/", "thumbnails/"}
+
+type Options struct {
+	Grace   time.Duration // only objects older than this are deleted
+	History time.Duration // revisions and automatic snapshots older than this no longer keep images; 0 = forever
+	DryRun  bool          // report orphans without deleting them
+}
+
+type Report struct {
+	Scanned int      `json:"scanned"`
+	Orphans []string `json:"orphans"` // deleted, or would be in a dry run
+	Bytes   int64    `json:"bytes"`
+	Failed  int      `json:"failed"`
+}
+
+type object struct {
+	key  string
+	size int64
+}
+
+// Sweep lists the store, diffs it against every key the database refers to
+// and deletes the orphans older than opts.Grace. Images referenced only by
+// card history or automatic snapshots from within opts.History, or by a
+// manual snapshot, are kept so undo and restore still work.
+func Sweep(ctx context.Context, db *sql.DB, store storage.Store, opts Options) (Report, error) {
+	var report Report
+	cutoff := time.Now().Add(-opts.Grace)
+
+	// List before loading references: anything referenced by the time the
+	// references are read is then safe, however long the listing took
+	var candidates []object
+	for _, prefix := range Prefixes {
+		err := store.List(ctx, prefix, func(key string, info storage.Info) error {
+			report.Scanned++
+			if info.ModTime.Before(cutoff) {
+				candidates = append(candidates, object{key, info.Size})
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	history := time.Unix(0, 0) // all history counts
+	if opts.History > 0 {
+		history = time.Now().Add(-opts.History)
+	}
+	refs, err := referencedKeys(db, cutoff, history)
+	if err != nil {
+		return report, err
+	}
+
+	for _, obj := range candidates {
+		if refs[obj.key] {
+			continue
+		}
+		// A card may have picked the image up since the references were read
+		if live, err := stillReferenced(db, obj.key, cutoff); err != nil || live {
+			if err != nil {
+				report.Failed++
+				log.Printf("gc: failed to re-check %s: %v", obj.key, err)
+			}
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, obj.key)
+		report.Bytes += obj.size
+		if opts.DryRun {
+			continue
+		}
+		if err := store.Delete(ctx, obj.key); err != nil {
+			report.Failed++
+			log.Printf("gc: failed to delete %s: %v", obj.key, err)
+			continue
+		}
+		if _, err := db.Exec("DELETE FROM image_objects WHERE storage_key = ?", obj.key); err != nil {
+			log.Printf("gc: failed to forget %s: %v", obj.key, err)
+		}
+		if err := quota.Release(db, obj.key); err != nil {
+			log.Printf("gc: failed to release quota for %s: %v", obj.key, err)
+		}
+	}
+	return report, nil
+}
+
+// Run sweeps every interval until ctx is cancelled
+func Run(ctx context.Context, db *sql.DB, store storage.Store, interval time.Duration, opts Options) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Sweep(ctx, db, store, opts)
+			if err != nil {
+				log.Printf("gc: sweep failed: %v", err)
+				continue
+			}
+			if len(report.Orphans) > 0 || report.Failed > 0 {
+				log.Printf("gc: scanned %d objects, removed %d orphans (%d bytes), %d failures",
+					report.Scanned, len(report.Orphans), report.Bytes, report.Failed)
+			}
+		}
+	}
+}
+
+// referencedKeys collects every storage key the database points at, with
+// the variants of each referenced image. Images that cards count on, or that
+// were uploaded again since cutoff, are included, as are those in revisions
+// and automatic snapshots made since history and in manual snapshots.
+func referencedKeys(db *sql.DB, cutoff, history time.Time) (map[string]bool, error) {
+	refs := map[string]bool{}
+	add := func(ref string) {
+		if key, ok := storage.KeyFromURL(ref, "images/"); ok {
+			refs[key] = true
+			for _, size := range imageproc.VariantSizes {
+				refs[imageproc.VariantKey(key, size)] = true
+			}
+		} else if key, ok := storage.KeyFromURL(ref, "thumbnails/"); ok {
+			refs[key] = true
+		}
+	}
+
+	queries := []string{
+		"SELECT image_url FROM cards WHERE image_url IS NOT NULL AND image_url <> ''",
+		"SELECT thumbnail_url FROM boards WHERE thumbnail_url IS NOT NULL AND thumbnail_url <> ''",
+	}
+	for _, q := range queries {
+		if err := scanStrings(db, q, add); err != nil {
+			return nil, err
+		}
+	}
+	queries = []string{
+		"SELECT JSON_UNQUOTE(JSON_EXTRACT(before_state, '$.image_url')) FROM card_revisions WHERE created_at > ? AND JSON_EXTRACT(before_state, '$.image_url') IS NOT NULL",
+		"SELECT JSON_UNQUOTE(JSON_EXTRACT(after_state, '$.image_url')) FROM card_revisions WHERE created_at > ? AND JSON_EXTRACT(after_state, '$.image_url') IS NOT NULL",
+	}
+	for _, q := range queries {
+		if err := scanStrings(db, q, add, history.UTC()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanStrings(db, "SELECT storage_key FROM image_objects WHERE ref_count > 0 OR last_uploaded_at > ?", add, cutoff.UTC()); err != nil {
+		return nil, err
+	}
+
+	// Snapshots hold whole boards; pull out just the image URLs
+	err := scanStrings(db,
+		`SELECT JSON_EXTRACT(data, '$.cards[*].image_url') FROM board_snapshots
+         WHERE (reason = 'manual' OR created_at > ?) AND JSON_EXTRACT(data, '$.cards[*].image_url') IS NOT NULL`,
+		func(raw string) {
+			var urls []string
+			if err := json.Unmarshal([]byte(raw), &urls); err != nil {
+				log.Printf("gc: unreadable snapshot image list: %v", err)
+				return
+			}
+			for _, u := range urls {
+				add(u)
+			}
+		}, history.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func scanStrings(db *sql.DB, query string, fn func(string), args ...interface{}) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return err
+		}
+		fn(s)
+	}
+	return rows.Err()
+}
+
+// stillReferenced checks the live tables for a single key. A variant counts
+// as referenced when its original is.
+func stillReferenced(db *sql.DB, key string, cutoff time.Time) (bool, error) {
+	original := stem(key) + ".%"
+	var n int
+	err := db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM cards WHERE image_url = ? OR image_url LIKE ?)
+             + (SELECT COUNT(*) FROM boards WHERE thumbnail_url = ?)
+             + (SELECT COUNT(*) FROM image_objects WHERE (storage_key = ? OR storage_key LIKE ?) AND (ref_count > 0 OR last_uploaded_at > ?))`,
+		key, original, key, key, original, cutoff.UTC(),
+	).Scan(&n)
+	return n > 0, err
+}
+
+// stem drops the extension and variant size from a key:
+// "images/sha256/<hash>_256.png" -> "images/sha256/<hash>"
+func stem(key string) string {
+	key = strings.TrimSuffix(key, path.Ext(key))
+	if i := strings.LastIndexByte(key, '_'); i > strings.LastIndexByte(key, '/') {
+		key = key[:i]
+	}
+	return key
+}
@@ -0,0 +1,320 @@
+// Package imageproc validates uploaded images, strips their metadata and
+// renders the smaller variants the canvas shows when zoomed out.
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// Pixel limits, checked from the header before anything is decoded. A
+// decoded image takes 4 bytes a pixel, so MaxPixels keeps one near 64MB.
+const (
+	MaxPixels = 16_000_000
+	MaxSide   = 12_000
+)
+
+// decodeSlots bounds how many images are decoded and resized at once, so
+// concurrent uploads cannot multiply the memory a single one needs
+var decodeSlots = make(chan struct{}, 2)
+
+// VariantSizes are the longest-side widths rendered for every image that is
+// larger than them
+var VariantSizes = []int{256, 1024}
+
+var (
+	ErrUnsupported = errors.New("file is not a supported image (png, jpeg, gif or webp)")
+	ErrTooLarge    = fmt.Errorf("image is larger than %d pixels or %dpx on a side", MaxPixels, MaxSide)
+)
+
+// Extensions for the content types Process accepts. SVG is left out on
+// purpose: it can carry script.
+var extensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Image is one encoded file ready to store
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// Result is a cleaned original plus its variants, keyed by size
+type Result struct {
+	Original Image
+	Variants map[int]Image
+}
+
+// Extension is the file extension for an accepted content type
+func Extension(contentType string) (string, bool) {
+	ext, ok := extensions[contentType]
+	return ext, ok
+}
+
+// Size is the number of bytes the original and its variants take up
+func (r *Result) Size() int64 {
+	n := int64(len(r.Original.Data))
+	for _, v := range r.Variants {
+		n += int64(len(v.Data))
+	}
+	return n
+}
+
+// Sniff returns the content type detected from data's first bytes and
+// whether it is one Process accepts
+func Sniff(data []byte) (string, bool) {
+	ct := http.DetectContentType(data)
+	if i := strings.IndexByte(ct, ';'); i != -1 {
+		ct = ct[:i]
+	}
+	_, ok := extensions[ct]
+	return ct, ok
+}
+
+// Process checks that data is a real image within the pixel limits and
+// returns it without EXIF/GPS or other metadata, along with its variants.
+//   - JPEG and PNG are re-encoded; JPEGs are rotated upright first, since
+//     their EXIF orientation is dropped with the rest of the metadata.
+//   - GIF keeps its frames so animations survive; comment and application
+//     extensions other than the looping ones are cut out.
+//   - WebP has its EXIF and XMP chunks cut out (there is no WebP encoder).
+func Process(data []byte) (*Result, error) {
+	ct, ok := Sniff(data)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != ct {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width > MaxSide || cfg.Height > MaxSide || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	decodeSlots <- struct{}{}
+	defer func() { <-decodeSlots }()
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	original := Image{ContentType: ct, Ext: extensions[ct], Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	switch ct {
+	case "image/jpeg", "image/png":
+		original.Data, err = encode(img, ct)
+		if err != nil {
+			return nil, err
+		}
+	case "image/gif":
+		original.Data, err = stripGIF(data)
+		if err != nil {
+			return nil, ErrUnsupported
+		}
+	case "image/webp":
+		original.Data, err = stripWebP(data)
+		if err != nil {
+			return nil, ErrUnsupported
+		}
+	}
+
+	res := &Result{Original: original, Variants: map[int]Image{}}
+	variantType := VariantContentType(ct)
+	for _, size := range VariantSizes {
+		if original.Width <= size && original.Height <= size {
+			continue
+		}
+		small := imaging.Fit(img, size, size, imaging.Lanczos)
+		b, err := encode(small, variantType)
+		if err != nil {
+			return nil, err
+		}
+		res.Variants[size] = Image{
+			Data:        b,
+			ContentType: variantType,
+			Ext:         extensions[variantType],
+			Width:       small.Bounds().Dx(),
+			Height:      small.Bounds().Dy(),
+		}
+	}
+	return res, nil
+}
+
+// VariantContentType is the format variants of an image are stored in:
+// JPEG for JPEG originals, PNG for everything else so transparency survives
+func VariantContentType(original string) string {
+	if original == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// VariantKey names the variant of size for an original's key, next to it:
+// "images/12/<id>.webp" -> "images/12/<id>_256.png"
+func VariantKey(key string, size int) string {
+	ext := path.Ext(key)
+	variantExt := ".png"
+	if ext == ".jpg" {
+		variantExt = ".jpg"
+	}
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(key, ext), size, variantExt)
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 88})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
+
+// stripWebP drops the EXIF and XMP chunks from a WebP file and clears their
+// flags in the VP8X header. Everything else, animation included, is kept.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrUnsupported
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	for p := 12; p < len(data); {
+		if p+8 > len(data) {
+			return nil, ErrUnsupported
+		}
+		fourCC := string(data[p : p+4])
+		size := int(uint32(data[p+4]) | uint32(data[p+5])<<8 | uint32(data[p+6])<<16 | uint32(data[p+7])<<24)
+		end := p + 8 + size + size%2 // chunks are padded to an even length
+		if end > len(data) {
+			return nil, ErrUnsupported
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[p:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP present flags
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[p:end]...)
+		}
+		p = end
+	}
+
+	riff := uint32(len(out) - 8)
+	out[4], out[5], out[6], out[7] = byte(riff), byte(riff>>8), byte(riff>>16), byte(riff>>24)
+	return out, nil
+}
+
+// Application extensions that control animation and are kept by stripGIF
+var gifLoopApps = map[string]bool{
+	"NETSCAPE2.0": true,
+	"ANIMEXTS1.0": true,
+}
+
+// stripGIF drops comment extensions and application extensions (XMP, ICC
+// and the like) from a GIF file, keeping the frames, their timing and the
+// loop count. Anything after the trailer is dropped too.
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, ErrUnsupported
+	}
+	p := 13 + colorTableSize(data[10])
+	if p > len(data) {
+		return nil, ErrUnsupported
+	}
+	out := make([]byte, p, len(data))
+	copy(out, data[:p])
+
+	for p < len(data) {
+		start := p
+		switch data[p] {
+		case 0x3B: // trailer
+			return append(out, 0x3B), nil
+
+		case 0x2C: // image descriptor, colour table, LZW code size, data
+			if p+11 > len(data) {
+				return nil, ErrUnsupported
+			}
+			end, err := skipSubBlocks(data, p+11+colorTableSize(data[p+9]))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, data[start:end]...)
+			p = end
+
+		case 0x21: // extension: label, then sub-blocks
+			if p+2 > len(data) {
+				return nil, ErrUnsupported
+			}
+			label := data[p+1]
+			end, err := skipSubBlocks(data, p+2)
+			if err != nil {
+				return nil, err
+			}
+			keep := true
+			switch label {
+			case 0xFE: // comment
+				keep = false
+			case 0xFF: // application; the first sub-block names it
+				keep = end > p+14 && data[p+2] == 11 && gifLoopApps[string(data[p+3:p+14])]
+			}
+			if keep {
+				out = append(out, data[start:end]...)
+			}
+			p = end
+
+		default:
+			return nil, ErrUnsupported
+		}
+	}
+	return nil, ErrUnsupported // no trailer
+}
+
+// colorTableSize is the length of the colour table a GIF screen or image
+// descriptor's packed byte announces
+func colorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << (packed&0x07 + 1)
+}
+
+// skipSubBlocks returns the offset just past the data sub-blocks starting at
+// p, terminator included
+func skipSubBlocks(data []byte, p int) (int, error) {
+	for {
+		if p >= len(data) {
+			return 0, ErrUnsupported
+		}
+		n := int(data[p])
+		p++
+		if n == 0 {
+			return p, nil
+		}
+		p += n
+	}
+}
@@ -0,0 +1,134 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// riff builds a WebP file from already encoded chunks
+func riff(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(body)))
+	return append(out, body...)
+}
+
+// chunk encodes a RIFF chunk, padding odd-length payloads
+func chunk(fourCC string, payload []byte) []byte {
+	out := []byte(fourCC)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(payload)))
+	out = append(out, payload...)
+	if len(payload)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func TestStripWebP(t *testing.T) {
+	vp8x := func(flags byte) []byte { return chunk("VP8X", []byte{flags, 0, 0, 0, 0, 0, 0, 0, 0, 0}) }
+	frame := chunk("VP8 ", []byte{1, 2, 3, 4})
+	odd := chunk("VP8L", []byte{1, 2, 3}) // padded to four bytes
+	exif := chunk("EXIF", []byte("Exif\x00\x00GPS"))
+	xmp := chunk("XMP ", []byte("<x:xmpmeta/>"))
+
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte // nil means ErrUnsupported
+	}{
+		{"plain", riff(frame), riff(frame)},
+		{"odd-length chunk kept with padding", riff(odd), riff(odd)},
+		{"metadata removed", riff(vp8x(0x0C), frame, exif, xmp), riff(vp8x(0), frame)},
+		{"other flags kept", riff(vp8x(0x1E), frame, exif), riff(vp8x(0x12), frame)},
+		{"odd-length metadata removed", riff(odd, chunk("EXIF", []byte{1})), riff(odd)},
+		{"too short", []byte("RIFF\x00\x00"), nil},
+		{"not riff", append([]byte("RIFX\x04\x00\x00\x00WEBP"), frame...), nil},
+		{"not webp", append([]byte("RIFF\x04\x00\x00\x00WAVE"), frame...), nil},
+		{"truncated chunk header", append(riff(frame), 'E', 'X', 'I'), nil},
+		{"chunk past the end", riff(frame, []byte("EXIF\xff\x00\x00\x00ab")), nil},
+		{"missing padding byte", riff(frame, []byte("EXIF\x03\x00\x00\x00abc")), nil},
+		{"huge chunk size", riff([]byte("VP8 \xff\xff\xff\xff")), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripWebP(tt.in)
+			if tt.want == nil {
+				if err != ErrUnsupported {
+					t.Fatalf("err = %v, want ErrUnsupported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripGIF(t *testing.T) {
+	pal := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 2; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), pal))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	clean := buf.Bytes()
+
+	// Metadata goes in front of the first frame's graphic control extension
+	first := bytes.Index(clean, []byte{0x21, 0xF9})
+	if first < 0 {
+		t.Fatal("no graphic control extension in encoded GIF")
+	}
+	comment := []byte{0x21, 0xFE, 5, 'h', 'e', 'l', 'l', 'o', 0}
+	xmp := append([]byte{0x21, 0xFF, 11}, "XMP DataXMP"...)
+	xmp = append(xmp, 3, '<', 'x', '>', 0)
+	withMeta := append(append(append(append([]byte(nil), clean[:first]...), comment...), xmp...), clean[first:]...)
+
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte // nil means ErrUnsupported
+	}{
+		{"clean", clean, clean},
+		{"comment and xmp removed", withMeta, clean},
+		{"trailing bytes dropped", append(append([]byte(nil), clean...), "junk"...), clean},
+		{"not gif", []byte("PNG\x00 not a gif at all"), nil},
+		{"no trailer", clean[:len(clean)-1], nil},
+		{"truncated sub-block", withMeta[:first+4], nil},
+		{"unknown block", append(append([]byte(nil), clean[:first]...), 0x99), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripGIF(tt.in)
+			if tt.want == nil {
+				if err != ErrUnsupported {
+					t.Fatalf("err = %v, want ErrUnsupported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("got %x\nwant %x", got, tt.want)
+			}
+			if _, err := gif.DecodeAll(bytes.NewReader(got)); err != nil {
+				t.Fatalf("stripped GIF does not decode: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+)
+
+// CSV import limits
+const (
+	MaxCSVBytes = 5 << 20
+	MaxCSVRows  = 1000
+)
+
+// Grid for rows without coordinates, placed below the existing cards
+const (
+	gridColumns = 5
+	gridGap     = 40
+)
+
+// RowError is a CSV row that cannot become a card. Row counts the header as
+// row 1, like a spreadsheet does.
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// CSVError is a CSV that was not imported; Rows says what was wrong with it
+type CSVError struct {
+	Message string
+	Rows    []RowError
+}
+
+func (e *CSVError) Error() string {
+	return e.Message
+}
+
+// ImportCSV creates a card on boardID for every row of a CSV with a header
+// naming its columns: text (required), and optionally x, y, kind ("text" or
+// "image") and image_url. Image rows without image_url take the URL from
+// text. Rows without x and y are laid out on a grid below the board's
+// existing cards. Blank rows are skipped.
+//
+// Every row is checked before anything is written, and then all cards are
+// created in one transaction under actor. A *CSVError lists the rows that
+// failed; nothing is created in that case. Callers are responsible for the
+// permission check.
+func ImportCSV(db *sql.DB, boardID int64, actor card.Actor, r io.Reader) ([]card.BatchResult, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, &CSVError{Message: "CSV is empty"}
+	}
+	if err != nil {
+		return nil, &CSVError{Message: "Invalid CSV: " + err.Error()}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // Excel adds a BOM
+		columns[name] = i
+	}
+	if _, ok := columns["text"]; !ok {
+		return nil, &CSVError{Message: "CSV header must have a text column"}
+	}
+
+	var ops []card.BatchOp
+	var rows []int // CSV row of each op
+	var bad []RowError
+	var unplaced []int
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &CSVError{Message: "Invalid CSV", Rows: []RowError{{Row: parseErr.Line, Error: parseErr.Err.Error()}}}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if blank(record) {
+			continue
+		}
+		if len(ops)+len(bad) == MaxCSVRows {
+			return nil, &CSVError{Message: fmt.Sprintf("CSV has more than %d rows", MaxCSVRows)}
+		}
+
+		op, placed, msg := csvOp(record, columns)
+		if msg != "" {
+			bad = append(bad, RowError{Row: row, Error: msg})
+			continue
+		}
+		if !placed {
+			unplaced = append(unplaced, len(ops))
+		}
+		ops = append(ops, op)
+		rows = append(rows, row)
+	}
+	if len(bad) > 0 {
+		return nil, &CSVError{Message: "Some rows are invalid", Rows: bad}
+	}
+	if len(ops) == 0 {
+		return nil, &CSVError{Message: "CSV has no rows"}
+	}
+
+	if len(unplaced) > 0 {
+		existing, err := card.GetCardsByBoard(db, boardID)
+		if err != nil {
+			return nil, err
+		}
+		placeOnGrid(existing, ops, unplaced)
+	}
+
+	results, err := card.ApplyBatch(db, boardID, ops, actor)
+	var berr *card.BatchError
+	if errors.As(err, &berr) {
+		return nil, &CSVError{Message: "Some rows are invalid", Rows: []RowError{{Row: rows[berr.Index], Error: berr.Message}}}
+	}
+	return results, err
+}
+
+// csvOp turns one record into a create operation. placed is false when the
+// row had no coordinates; msg is set when the row is invalid.
+func csvOp(record []string, columns map[string]int) (op card.BatchOp, placed bool, msg string) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	op.Op = card.OpCreate
+	op.Kind = strings.ToLower(field("kind"))
+	text := field("text")
+	switch op.Kind {
+	case "", "text":
+		if text == "" {
+			return op, false, "text is empty"
+		}
+		op.Text = &text
+	case "image":
+		op.ImageURL = field("image_url")
+		if op.ImageURL == "" {
+			op.ImageURL = text
+		}
+		if op.ImageURL == "" {
+			return op, false, "image rows need an image_url"
+		}
+	default:
+		return op, false, "kind must be 'text' or 'image'"
+	}
+
+	x, y := field("x"), field("y")
+	if x == "" && y == "" {
+		return op, false, ""
+	}
+	if x == "" || y == "" {
+		return op, false, "x and y must be given together"
+	}
+	fx, err := strconv.ParseFloat(x, 64)
+	if err != nil {
+		return op, false, "x is not a number"
+	}
+	fy, err := strconv.ParseFloat(y, 64)
+	if err != nil {
+		return op, false, "y is not a number"
+	}
+	op.PositionX, op.PositionY = &fx, &fy
+	return op, true, ""
+}
+
+// placeOnGrid gives the ops at indexes unplaced positions on a grid under
+// everything already on the board, gridColumns cards wide
+func placeOnGrid(existing []card.Card, ops []card.BatchOp, unplaced []int) {
+	var bounds image.Rectangle
+	for _, c := range existing {
+		bounds = bounds.Union(export.CardRect(c, nil))
+	}
+	left, top := bounds.Min.X, 0
+	if !bounds.Empty() {
+		top = bounds.Max.Y + gridGap
+	}
+
+	rowHeight := 0
+	for n, i := range unplaced {
+		if n > 0 && n%gridColumns == 0 {
+			top += rowHeight + gridGap
+			rowHeight = 0
+		}
+		x := float64(left + (n%gridColumns)*(export.TextCardWidth+gridGap))
+		y := float64(top)
+		ops[i].PositionX, ops[i].PositionY = &x, &y
+
+		c := card.Card{Kind: ops[i].Kind}
+		if ops[i].Text != nil {
+			c.Text = *ops[i].Text
+		}
+		if h := export.CardRect(c, nil).Dy(); h > rowHeight {
+			rowHeight = h
+		}
+	}
+}
+
+func blank(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
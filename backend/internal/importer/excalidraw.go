@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+)
+
+// Excalidraw scenes, as written by export.Excalidraw or drawn in Excalidraw
+// itself. Rectangles, ellipses and diamonds with text become text cards,
+// image elements image cards, and arrows bound at both ends links. Shapes
+// without text and freehand drawing are dropped.
+
+// FromExcalidraw converts an Excalidraw scene into a Document titled title.
+// Positions and image sizes are kept; text cards take the canvas's fixed
+// width, and rotation is ignored.
+func FromExcalidraw(data []byte, title string) (*export.Document, error) {
+	var scene export.ExcalidrawScene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		return nil, &ImportError{Reason: "not a valid Excalidraw scene: " + err.Error()}
+	}
+	if scene.Type != "excalidraw" {
+		return nil, &ImportError{Reason: `not an Excalidraw scene (type must be "excalidraw")`}
+	}
+
+	elements := map[string]export.ExcalidrawElement{}
+	labels := map[string]string{} // text bound inside another element, by container ID
+	for _, el := range scene.Elements {
+		if el.IsDeleted {
+			continue
+		}
+		elements[el.ID] = el
+		if el.Type == "text" && el.ContainerID != nil {
+			labels[*el.ContainerID] = excalidrawText(el)
+		}
+	}
+
+	doc := &export.Document{Format: export.DocumentFormat, Version: export.DocumentVersion, ExportedAt: time.Now().UTC()}
+	doc.Board.Title = title
+	ids := map[string]int64{}
+	add := func(el export.ExcalidrawElement, c export.DocumentCard) {
+		c.ID = int64(len(doc.Cards) + 1)
+		c.PositionX, c.PositionY = el.X, el.Y
+		ids[el.ID] = c.ID
+		doc.Cards = append(doc.Cards, c)
+	}
+	for _, el := range scene.Elements {
+		if el.IsDeleted {
+			continue
+		}
+		switch el.Type {
+		case "text":
+			if el.ContainerID == nil || elements[*el.ContainerID].ID == "" {
+				if text := excalidrawText(el); text != "" {
+					add(el, export.DocumentCard{Kind: "text", Text: text})
+				}
+			}
+		case "rectangle", "ellipse", "diamond":
+			if text, ok := labels[el.ID]; ok && text != "" {
+				add(el, export.DocumentCard{Kind: "text", Text: text})
+			} else if isExternalImage(el) {
+				add(el, export.DocumentCard{Kind: "image", Image: &export.DocumentImage{URL: *el.Link}, Width: size(el.Width), Height: size(el.Height)})
+			}
+		case "image":
+			file, ok := scene.Files[el.FileID]
+			if !ok {
+				continue
+			}
+			contentType, data, ok := decodeDataURL(file.DataURL)
+			if !ok {
+				continue
+			}
+			add(el, export.DocumentCard{
+				Kind:   "image",
+				Image:  &export.DocumentImage{ContentType: contentType, Data: data},
+				Width:  size(el.Width),
+				Height: size(el.Height),
+			})
+		}
+	}
+
+	for _, el := range scene.Elements {
+		if el.IsDeleted || (el.Type != "arrow" && el.Type != "line") || el.StartBinding == nil || el.EndBinding == nil {
+			continue
+		}
+		from, to := ids[el.StartBinding.ElementID], ids[el.EndBinding.ElementID]
+		if from == 0 || to == 0 || from == to {
+			continue
+		}
+		link := export.DocumentLink{SourceCardID: from, TargetCardID: to, Label: linkLabel(labels[el.ID]), Style: card.LinkStyleSolid}
+		switch el.StrokeStyle {
+		case "dashed":
+			link.Style = card.LinkStyleDashed
+		case "dotted":
+			link.Style = card.LinkStyleDotted
+		}
+		start, end := el.StartArrowhead != nil, el.EndArrowhead != nil
+		if start && !end {
+			link.SourceCardID, link.TargetCardID = to, from
+		}
+		link.Directed = start || end
+		doc.Links = append(doc.Links, link)
+	}
+	return doc, nil
+}
+
+// imageExtensions are the link file extensions taken to point at a picture
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+}
+
+// isExternalImage reports whether a shape stands for an image by URL: the
+// rectangles package export writes external image cards as, marked in their
+// custom data, or any shape whose http(s) link names an image file. Other
+// linked shapes are just links and are dropped.
+func isExternalImage(el export.ExcalidrawElement) bool {
+	if el.Link == nil {
+		return false
+	}
+	u, err := url.Parse(*el.Link)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return false
+	}
+	return el.CustomData["kind"] == "image" || imageExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+func excalidrawText(el export.ExcalidrawElement) string {
+	if el.OriginalText != "" {
+		return strings.TrimSpace(el.OriginalText)
+	}
+	return strings.TrimSpace(el.Text)
+}
+
+func size(v float64) *float64 {
+	if v <= 0 {
+		return nil
+	}
+	return &v
+}
+
+// decodeDataURL splits a base64 data: URL into its content type and bytes
+func decodeDataURL(s string) (string, []byte, bool) {
+	rest, ok := strings.CutPrefix(s, "data:")
+	if !ok {
+		return "", nil, false
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return "", nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, false
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, true
+}
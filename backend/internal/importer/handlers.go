@@ -0,0 +1,193 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+type ImportHandler struct {
+	DB    *sql.DB
+	Store storage.Store
+}
+
+// POST /boards/import?format={json|excalidraw|miro}&title=
+// body: by format, a Document as written by GET /boards/{id}/export.json
+// (the default), an Excalidraw scene, or a sticky-note board (see FromMiro).
+// title names the board for formats that carry no title.
+// Creates a new board owned by the caller. Responds with the board's ID and
+// the new ID of every card, keyed by its ID in the document.
+func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportBytes)
+	title := r.URL.Query().Get("title")
+	if title == "" {
+		title = "Imported board"
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "excalidraw" && format != "miro" {
+		middleware.JSONError(w, "format must be json, excalidraw or miro", http.StatusBadRequest)
+		return
+	}
+
+	var doc *export.Document
+	var err error
+	var tooLarge *http.MaxBytesError
+	if format == "" || format == "json" {
+		// Decode straight from the body rather than holding a copy of it too
+		doc = &export.Document{}
+		err = json.NewDecoder(r.Body).Decode(doc)
+		if errors.As(err, &tooLarge) {
+			middleware.JSONError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("title") != "" {
+			doc.Board.Title = title
+		}
+	} else {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			middleware.JSONError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if format == "excalidraw" {
+			doc, err = FromExcalidraw(data, title)
+		} else {
+			doc, err = FromMiro(data, title)
+		}
+	}
+
+	var boardID int64
+	var ids map[int64]int64
+	if err == nil {
+		boardID, ids, err = Import(r.Context(), h.DB, h.Store, userID, doc)
+	}
+	var importErr *ImportError
+	if errors.As(err, &importErr) {
+		middleware.JSONError(w, importErr.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+	if err == quota.ErrQuotaExceeded {
+		middleware.JSONError(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	if err != nil {
+		log.Printf("board import failed: %v", err)
+		middleware.JSONError(w, "Failed to import board", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       boardID,
+		"title":    doc.Board.Title,
+		"card_ids": ids,
+	})
+}
+
+type CSVHandler struct {
+	DB *sql.DB
+}
+
+// POST /boards/{id}/import/csv
+// perms: owner or edit
+// body: the CSV itself, or a multipart form with it as "file"; see ImportCSV
+// Responds 201 with the created cards, or 422 with the rows that failed.
+func (h *CSVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /boards/{id}/import/csv
+	if len(parts) != 5 || parts[1] != "boards" || parts[3] != "import" || parts[4] != "csv" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm != board.PermissionOwner && perm != board.PermissionEdit {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxCSVBytes+1<<20) // room for a form around the file
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(MaxCSVBytes); err != nil {
+			middleware.JSONError(w, "Invalid form data or file too large", http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			middleware.JSONError(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	results, err := ImportCSV(h.DB, boardID, card.UserActor(userID), body)
+	var csvErr *CSVError
+	if errors.As(err, &csvErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": csvErr.Message,
+			"rows":  csvErr.Rows,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("CSV import on board %d failed: %v", boardID, err)
+		middleware.JSONError(w, "Failed to import CSV", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
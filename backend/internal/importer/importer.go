@@ -0,0 +1,188 @@
+// Package importer creates boards and cards from files made elsewhere: JSON
+// documents and Excalidraw scenes written by package export, sticky-note
+// boards from other tools and CSV files. Documents are read into
+// export.Document and placed with export's card sizes.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// Import limits. MaxImportBytes leaves room for a document's images at
+// export.MaxDocumentImageBytes once base64 encoded, plus its cards and links.
+const (
+	MaxImportBytes = 128 << 20
+	MaxImportCards = 5000
+)
+
+// maxLinkLabel is card's limit on link labels; converters cut longer ones
+const maxLinkLabel = 255
+
+// maxBoardTitle is the length of boards.title, in characters
+const maxBoardTitle = 255
+
+// ImportError means the document cannot be imported as it is
+type ImportError struct {
+	Reason string
+}
+
+func (e *ImportError) Error() string {
+	return "import failed: " + e.Reason
+}
+
+// Import recreates doc as a new board owned by userID. Returns the board's
+// ID and the new ID of every card, keyed by its ID in the document.
+//
+// Embedded images go through the same pipeline as uploads: they are stored
+// once by content and count against the owner's quota. If anything fails the
+// new board is deleted again.
+func Import(ctx context.Context, db *sql.DB, store storage.Store, userID int64, doc *export.Document) (int64, map[int64]int64, error) {
+	if err := validateDocument(doc); err != nil {
+		return 0, nil, err
+	}
+	boardID, err := board.CreateBoard(db, userID, doc.Board.Title)
+	if err != nil {
+		return 0, nil, err
+	}
+	ids, err := importCards(ctx, db, store, boardID, userID, doc)
+	if err != nil {
+		if _, derr := board.DeleteBoard(db, boardID, userID); derr != nil {
+			log.Printf("WARN: failed to remove half-imported board %d: %v", boardID, derr)
+		}
+		return 0, nil, err
+	}
+	return boardID, ids, nil
+}
+
+func validateDocument(doc *export.Document) error {
+	if doc.Format != export.DocumentFormat {
+		return &ImportError{Reason: fmt.Sprintf("format must be %q", export.DocumentFormat)}
+	}
+	if doc.Version < 1 || doc.Version > export.DocumentVersion {
+		return &ImportError{Reason: fmt.Sprintf("unsupported document version %d", doc.Version)}
+	}
+	doc.Board.Title = strings.TrimSpace(doc.Board.Title)
+	if doc.Board.Title == "" {
+		return &ImportError{Reason: "board title must not be empty"}
+	}
+	if utf8.RuneCountInString(doc.Board.Title) > maxBoardTitle {
+		return &ImportError{Reason: fmt.Sprintf("board title is longer than %d characters", maxBoardTitle)}
+	}
+	if len(doc.Cards) > MaxImportCards {
+		return &ImportError{Reason: fmt.Sprintf("at most %d cards can be imported", MaxImportCards)}
+	}
+
+	seen := make(map[int64]bool, len(doc.Cards))
+	for _, c := range doc.Cards {
+		if seen[c.ID] {
+			return &ImportError{Reason: fmt.Sprintf("card id %d is used twice", c.ID)}
+		}
+		seen[c.ID] = true
+		switch c.Kind {
+		case "", "text":
+		case "image":
+			if c.Image == nil || (len(c.Image.Data) == 0 && c.Image.URL == "") {
+				return &ImportError{Reason: fmt.Sprintf("card %d: image cards need image data or a url", c.ID)}
+			}
+			if len(c.Image.Data) > card.MaxImageBytes {
+				return &ImportError{Reason: fmt.Sprintf("card %d: image is larger than %d bytes", c.ID, card.MaxImageBytes)}
+			}
+			if len(c.Image.Data) == 0 && !strings.HasPrefix(c.Image.URL, "https://") && !strings.HasPrefix(c.Image.URL, "http://") {
+				return &ImportError{Reason: fmt.Sprintf("card %d: image url must be http(s)", c.ID)}
+			}
+		default:
+			return &ImportError{Reason: fmt.Sprintf("card %d: kind must be 'text' or 'image'", c.ID)}
+		}
+	}
+	for _, l := range doc.Links {
+		if !seen[l.SourceCardID] || !seen[l.TargetCardID] {
+			return &ImportError{Reason: fmt.Sprintf("link %d -> %d points at a card that is not in the document", l.SourceCardID, l.TargetCardID)}
+		}
+	}
+	return nil
+}
+
+// importCards stores the images first, then creates the cards and links in
+// one transaction
+func importCards(ctx context.Context, db *sql.DB, store storage.Store, boardID, userID int64, doc *export.Document) (map[int64]int64, error) {
+	images := map[int64]string{}
+	for _, c := range doc.Cards {
+		if c.Kind != "image" {
+			continue
+		}
+		if len(c.Image.Data) == 0 {
+			images[c.ID] = c.Image.URL
+			continue
+		}
+		key, err := card.StoreImage(ctx, db, store, boardID, card.UserActor(userID), c.Image.Data)
+		if err == imageproc.ErrUnsupported || err == imageproc.ErrTooLarge {
+			return nil, &ImportError{Reason: fmt.Sprintf("card %d: %v", c.ID, err)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		images[c.ID] = key
+	}
+
+	tx, err := card.Begin(db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make(map[int64]int64, len(doc.Cards))
+	for _, c := range doc.Cards {
+		var id int64
+		if c.Kind == "image" {
+			id, err = card.CreateImageCard(tx, boardID, images[c.ID], c.PositionX, c.PositionY, c.Width, c.Height)
+		} else {
+			id, err = card.CreateCard(tx, boardID, c.Text, c.PositionX, c.PositionY)
+		}
+		if err == card.ErrImageNotConfirmed {
+			return nil, &ImportError{Reason: fmt.Sprintf("card %d: image url does not point at an image stored for this board", c.ID)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids[c.ID] = id
+	}
+
+	for _, l := range doc.Links {
+		_, err := card.CreateLink(tx, boardID, ids[l.SourceCardID], ids[l.TargetCardID], l.Label, l.Style, l.Directed)
+		if err == card.ErrLinkEndpoint || err == card.ErrLinkStyle || err == card.ErrLinkLabel {
+			return nil, &ImportError{Reason: fmt.Sprintf("link %d -> %d: %v", l.SourceCardID, l.TargetCardID, err)}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = activity.Record(tx, boardID, &userID, nil, activity.TypeBoardImported, map[string]interface{}{
+		"cards": len(doc.Cards),
+		"links": len(doc.Links),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// linkLabel cuts a label from another tool down to what links can hold
+func linkLabel(s string) string {
+	if r := []rune(s); len(r) > maxLinkLabel {
+		return string(r[:maxLinkLabel])
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+)
+
+// Image links to other sites, "images/" in their path or not, come in as
+// image cards that keep the link
+func TestExternalImageLinks(t *testing.T) {
+	const link = "https://cdn.example.com/images/logo.png"
+	tests := []struct {
+		name  string
+		parse func() (*export.Document, error)
+	}{
+		{"excalidraw", func() (*export.Document, error) {
+			return FromExcalidraw([]byte(`{"type": "excalidraw", "elements": [
+				{"id": "a", "type": "rectangle", "x": 10, "y": 20, "width": 200, "height": 100, "link": "`+link+`"}
+			]}`), "Imported")
+		}},
+		{"miro", func() (*export.Document, error) {
+			return FromMiro([]byte(`{"items": [
+				{"id": "a", "type": "image", "data": {"imageUrl": "`+link+`"},
+				 "position": {"x": 110, "y": 70}, "geometry": {"width": 200, "height": 100}}
+			]}`), "Imported")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := tt.parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := validateDocument(doc); err != nil {
+				t.Fatalf("validateDocument: %v", err)
+			}
+			if len(doc.Cards) != 1 {
+				t.Fatalf("got %d cards, want 1", len(doc.Cards))
+			}
+			c := doc.Cards[0]
+			if c.Kind != "image" || c.Image == nil || c.Image.URL != link {
+				t.Fatalf("got %+v, want an image card linking to %s", c, link)
+			}
+			if c.PositionX != 10 || c.PositionY != 20 || c.Width == nil || *c.Width != 200 {
+				t.Errorf("card placed at %v,%v width %v, want 10,20 width 200", c.PositionX, c.PositionY, c.Width)
+			}
+		})
+	}
+}
+
+func TestValidateDocumentTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string // trimmed title, or "" when the document is refused
+	}{
+		{"Roadmap", "Roadmap"},
+		{"  Roadmap \n", "Roadmap"},
+		{strings.Repeat("é", maxBoardTitle), strings.Repeat("é", maxBoardTitle)},
+		{"", ""},
+		{" \t ", ""},
+		{strings.Repeat("é", maxBoardTitle+1), ""},
+	}
+	for _, tt := range tests {
+		doc := &export.Document{Format: export.DocumentFormat, Version: export.DocumentVersion}
+		doc.Board.Title = tt.title
+		err := validateDocument(doc)
+		if tt.want == "" {
+			if _, ok := err.(*ImportError); !ok {
+				t.Errorf("title %q: err = %v, want an ImportError", tt.title, err)
+			}
+			continue
+		}
+		if err != nil || doc.Board.Title != tt.want {
+			t.Errorf("title %q: got %q, %v; want %q", tt.title, doc.Board.Title, err, tt.want)
+		}
+	}
+}
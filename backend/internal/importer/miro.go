@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+)
+
+// Sticky-note boards in the shape of Miro's REST API: a list of items and a
+// list of connectors between them. Other tools' exports can be massaged into
+// it with a few lines of jq.
+//
+//	{
+//	  "items": [{"id": "a", "type": "sticky_note", "data": {"content": "<p>Idea</p>"},
+//	             "position": {"x": 0, "y": 0, "origin": "center"},
+//	             "geometry": {"width": 200, "height": 200}}],
+//	  "connectors": [{"startItem": {"id": "a"}, "endItem": {"id": "b"},
+//	                  "captions": [{"content": "leads to"}],
+//	                  "style": {"strokeStyle": "dashed", "endStrokeCap": "arrow"}}]
+//	}
+//
+// "data" is accepted in place of "items", as the API pages its results.
+type miroBoard struct {
+	Items      []miroItem      `json:"items"`
+	Data       []miroItem      `json:"data"`
+	Connectors []miroConnector `json:"connectors"`
+}
+
+type miroItem struct {
+	ID   string `json:"id"`
+	Type string `json:"type"` // sticky_note | text | shape | card | image
+	Data struct {
+		Content  string `json:"content"`
+		Title    string `json:"title"`
+		ImageURL string `json:"imageUrl"`
+		URL      string `json:"url"`
+	} `json:"data"`
+	Position struct {
+		X      float64 `json:"x"`
+		Y      float64 `json:"y"`
+		Origin string  `json:"origin"` // "center" (the default) or "top_left"
+	} `json:"position"`
+	Geometry struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	} `json:"geometry"`
+}
+
+type miroConnector struct {
+	StartItem struct {
+		ID string `json:"id"`
+	} `json:"startItem"`
+	EndItem struct {
+		ID string `json:"id"`
+	} `json:"endItem"`
+	Captions []struct {
+		Content string `json:"content"`
+	} `json:"captions"`
+	Style struct {
+		StrokeStyle    string `json:"strokeStyle"` // normal | dashed | dotted
+		StartStrokeCap string `json:"startStrokeCap"`
+		EndStrokeCap   string `json:"endStrokeCap"`
+	} `json:"style"`
+}
+
+// FromMiro converts a sticky-note board into a Document titled title.
+// Positions and image sizes are kept; text cards take the canvas's fixed
+// width. Images given as data: URLs are embedded, other image URLs are kept
+// as links.
+func FromMiro(data []byte, title string) (*export.Document, error) {
+	var in miroBoard
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, &ImportError{Reason: "not a valid sticky-note board: " + err.Error()}
+	}
+	items := in.Items
+	if items == nil {
+		items = in.Data
+	}
+
+	doc := &export.Document{Format: export.DocumentFormat, Version: export.DocumentVersion, ExportedAt: time.Now().UTC()}
+	doc.Board.Title = title
+	ids := map[string]int64{}
+	for _, it := range items {
+		x, y := it.Position.X, it.Position.Y
+		if it.Position.Origin != "top_left" {
+			x -= it.Geometry.Width / 2
+			y -= it.Geometry.Height / 2
+		}
+		c := export.DocumentCard{ID: int64(len(doc.Cards) + 1), PositionX: x, PositionY: y}
+
+		switch it.Type {
+		case "sticky_note", "text", "shape", "card":
+			c.Kind = "text"
+			c.Text = strings.TrimSpace(strings.Join([]string{plainText(it.Data.Title), plainText(it.Data.Content)}, "\n"))
+			if c.Text == "" {
+				continue
+			}
+		case "image":
+			url := it.Data.ImageURL
+			if url == "" {
+				url = it.Data.URL
+			}
+			c.Kind = "image"
+			if contentType, data, ok := decodeDataURL(url); ok {
+				c.Image = &export.DocumentImage{ContentType: contentType, Data: data}
+			} else if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+				c.Image = &export.DocumentImage{URL: url}
+			} else {
+				continue
+			}
+			c.Width, c.Height = size(it.Geometry.Width), size(it.Geometry.Height)
+		default:
+			continue
+		}
+		ids[it.ID] = c.ID
+		doc.Cards = append(doc.Cards, c)
+	}
+
+	for _, conn := range in.Connectors {
+		from, to := ids[conn.StartItem.ID], ids[conn.EndItem.ID]
+		if from == 0 || to == 0 || from == to {
+			continue
+		}
+		link := export.DocumentLink{SourceCardID: from, TargetCardID: to, Style: card.LinkStyleSolid}
+		switch conn.Style.StrokeStyle {
+		case "dashed":
+			link.Style = card.LinkStyleDashed
+		case "dotted":
+			link.Style = card.LinkStyleDotted
+		}
+		var captions []string
+		for _, caption := range conn.Captions {
+			if t := plainText(caption.Content); t != "" {
+				captions = append(captions, t)
+			}
+		}
+		link.Label = linkLabel(strings.Join(captions, " "))
+		start, end := capped(conn.Style.StartStrokeCap), capped(conn.Style.EndStrokeCap)
+		if start && !end {
+			link.SourceCardID, link.TargetCardID = to, from
+		}
+		link.Directed = start || end
+		doc.Links = append(doc.Links, link)
+	}
+	return doc, nil
+}
+
+func capped(strokeCap string) bool {
+	return strokeCap != "" && strokeCap != "none"
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// plainText turns the bit of HTML Miro keeps in content fields into text
+func plainText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,128 @@
+package presence
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Participant kinds
+const (
+	KindUser  = "user"  // JWT-authenticated user
+	KindGuest = "guest" // anonymous share-link visitor
+)
+
+type Cursor struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Participant is one live connection to a board. Presence is ephemeral and
+// never written to MySQL; it disappears when the socket closes.
+type Participant struct {
+	SessionID  string    `json:"session_id"`
+	Kind       string    `json:"kind"` // "user" | "guest"
+	UserID     int64     `json:"user_id,omitempty"`
+	Name       string    `json:"name"`
+	Permission string    `json:"permission"`
+	Cursor     *Cursor   `json:"cursor,omitempty"`
+	Selection  []int64   `json:"selection"`
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+// Tracker keeps the participants of every board, keyed by board ID
+type Tracker struct {
+	mu     sync.RWMutex
+	boards map[int64]map[string]*Participant
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{boards: make(map[int64]map[string]*Participant)}
+}
+
+// Join adds a participant to a board and returns it with its session ID set
+func (t *Tracker) Join(boardID int64, p Participant) Participant {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p.SessionID = uuid.New().String()
+	p.JoinedAt = time.Now()
+	if p.Selection == nil {
+		p.Selection = []int64{}
+	}
+
+	board, ok := t.boards[boardID]
+	if !ok {
+		board = make(map[string]*Participant)
+		t.boards[boardID] = board
+	}
+	board[p.SessionID] = &p
+	return p
+}
+
+// Leave removes a participant; it is a no-op for unknown sessions
+func (t *Tracker) Leave(boardID int64, sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	board, ok := t.boards[boardID]
+	if !ok {
+		return
+	}
+	delete(board, sessionID)
+	if len(board) == 0 {
+		delete(t.boards, boardID)
+	}
+}
+
+// SetCursor records a participant's cursor position on the canvas
+func (t *Tracker) SetCursor(boardID int64, sessionID string, x, y float64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.boards[boardID][sessionID]
+	if !ok {
+		return false
+	}
+	p.Cursor = &Cursor{X: x, Y: y}
+	return true
+}
+
+// SetSelection records which cards a participant currently has selected
+func (t *Tracker) SetSelection(boardID int64, sessionID string, cardIDs []int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.boards[boardID][sessionID]
+	if !ok {
+		return false
+	}
+	if cardIDs == nil {
+		cardIDs = []int64{}
+	}
+	p.Selection = cardIDs
+	return true
+}
+
+// List returns a copy of a board's participants, oldest first
+func (t *Tracker) List(boardID int64) []Participant {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	participants := make([]Participant, 0, len(t.boards[boardID]))
+	for _, p := range t.boards[boardID] {
+		cp := *p
+		if p.Cursor != nil {
+			cur := *p.Cursor
+			cp.Cursor = &cur
+		}
+		cp.Selection = append([]int64{}, p.Selection...)
+		participants = append(participants, cp)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].JoinedAt.Before(participants[j].JoinedAt)
+	})
+	return participants
+}
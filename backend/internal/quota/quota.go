@@ -0,0 +1,186 @@
+// Package quota tracks how many bytes each board keeps in blob storage and
+// enforces a per-owner limit. Uploads through share links count against the
+// board owner.
+//
+// The limit applies to live bytes: thumbnails, images on one of the owner's
+// cards and uploads from the last day that may not be on a card yet. Images
+// taken off every card stay stored until gc collects them, but count as
+// reclaimable rather than against the limit.
+package quota
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultLimit applies when STORAGE_QUOTA_BYTES is unset
+const DefaultLimit = 1 << 30
+
+// unplacedWindow is how long an upload counts as live without being on a
+// card, so images cannot be uploaded past the limit before placing them
+const unplacedWindow = 24 * time.Hour
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// DB is satisfied by *sql.DB, *sql.Tx and the card package's *Tx
+type DB interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+var (
+	limitOnce sync.Once
+	limit     int64
+)
+
+// Limit is the number of bytes each user may store across the boards they
+// own, from STORAGE_QUOTA_BYTES. 0 means unlimited.
+func Limit() int64 {
+	limitOnce.Do(func() {
+		limit = DefaultLimit
+		if v := os.Getenv("STORAGE_QUOTA_BYTES"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				log.Printf("WARN: invalid STORAGE_QUOTA_BYTES %q, using %d", v, int64(DefaultLimit))
+				return
+			}
+			limit = n
+		}
+	})
+	return limit
+}
+
+// Check returns ErrQuotaExceeded if storing incoming more bytes on the board
+// would take its owner's live bytes over the limit
+func Check(db DB, boardID, incoming int64) error {
+	max := Limit()
+	if max == 0 {
+		return nil
+	}
+	var ownerID int64
+	err := db.QueryRow("SELECT owner_id FROM boards WHERE id = ?", boardID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	live, _, err := ownerBytes(db, ownerID)
+	if err != nil {
+		return err
+	}
+	if live+incoming > max {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// ownerBytes reports the bytes an owner's boards keep stored, and how many of
+// them are live. Uploads count once per board that uploaded them.
+func ownerBytes(db DB, ownerID int64) (live, stored int64, err error) {
+	err = db.QueryRow(
+		`SELECT
+             (SELECT COALESCE(SUM(s.image_bytes + s.thumbnail_bytes), 0)
+              FROM board_storage s JOIN boards b ON b.id = s.board_id WHERE b.owner_id = ?),
+             (SELECT COALESCE(SUM(s.thumbnail_bytes), 0)
+              FROM board_storage s JOIN boards b ON b.id = s.board_id WHERE b.owner_id = ?)
+             + (SELECT COALESCE(SUM(u.size), 0)
+                FROM image_uploads u
+                JOIN boards b ON b.id = u.board_id
+                LEFT JOIN (
+                    SELECT DISTINCT c.image_url FROM cards c JOIN boards cb ON cb.id = c.board_id
+                    WHERE cb.owner_id = ? AND c.kind = 'image'
+                ) used ON used.image_url = u.object_key
+                WHERE b.owner_id = ? AND u.status = 'confirmed'
+                  AND (used.image_url IS NOT NULL OR u.created_at > NOW() - INTERVAL ? SECOND))`,
+		ownerID, ownerID, ownerID, ownerID, int64(unplacedWindow.Seconds()),
+	).Scan(&stored, &live)
+	if live > stored {
+		live = stored
+	}
+	return live, stored, err
+}
+
+// CheckThumbnail is Check for a thumbnail of n bytes replacing the board's
+// current one
+func CheckThumbnail(db DB, boardID, n int64) error {
+	var current int64
+	err := db.QueryRow("SELECT thumbnail_bytes FROM board_storage WHERE board_id = ?", boardID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return Check(db, boardID, n-current)
+}
+
+// AddImageBytes records images stored on (or, negative, removed from) a board
+func AddImageBytes(db DB, boardID, delta int64) error {
+	_, err := db.Exec(
+		`INSERT INTO board_storage (board_id, image_bytes) VALUES (?, GREATEST(?, 0))
+         ON DUPLICATE KEY UPDATE image_bytes = GREATEST(image_bytes + ?, 0)`,
+		boardID, delta, delta,
+	)
+	return err
+}
+
+// SetThumbnailBytes records the size of a board's thumbnail; a board has at
+// most one, so it replaces rather than adds
+func SetThumbnailBytes(db DB, boardID, n int64) error {
+	_, err := db.Exec(
+		`INSERT INTO board_storage (board_id, thumbnail_bytes) VALUES (?, ?)
+         ON DUPLICATE KEY UPDATE thumbnail_bytes = VALUES(thumbnail_bytes)`,
+		boardID, n,
+	)
+	return err
+}
+
+// Release forgets the upload records for a deleted image key and credits
+// their bytes back to the boards that uploaded it. Keys without a record are
+// ignored.
+func Release(db DB, key string) error {
+	_, err := db.Exec(
+		`UPDATE board_storage s
+         JOIN (
+             SELECT board_id, SUM(COALESCE(size, 0)) AS n FROM image_uploads
+             WHERE object_key = ? AND status = 'confirmed'
+             GROUP BY board_id
+         ) u ON u.board_id = s.board_id
+         SET s.image_bytes = GREATEST(s.image_bytes - u.n, 0)`,
+		key,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM image_uploads WHERE object_key = ? OR storage_key = ?", key, key)
+	return err
+}
+
+// Usage summarises what a user owns
+type Usage struct {
+	Boards           int64 `json:"boards"`
+	Cards            int64 `json:"cards"`
+	StorageBytes     int64 `json:"storage_bytes"`     // live bytes, counted against the quota
+	ReclaimableBytes int64 `json:"reclaimable_bytes"` // images on no card, still stored until gc collects them
+	QuotaBytes       int64 `json:"quota_bytes"`       // 0 = unlimited
+}
+
+// ForUser reports usage across the boards userID owns
+func ForUser(db DB, userID int64) (Usage, error) {
+	u := Usage{QuotaBytes: Limit()}
+	err := db.QueryRow(
+		`SELECT
+             (SELECT COUNT(*) FROM boards WHERE owner_id = ?),
+             (SELECT COUNT(*) FROM cards c JOIN boards b ON b.id = c.board_id WHERE b.owner_id = ?)`,
+		userID, userID,
+	).Scan(&u.Boards, &u.Cards)
+	if err != nil {
+		return u, err
+	}
+	live, stored, err := ownerBytes(db, userID)
+	u.StorageBytes, u.ReclaimableBytes = live, stored-live
+	return u, err
+}
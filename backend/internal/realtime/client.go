@@ -0,0 +1,115 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 64 << 10
+	sendBuffer     = 64
+)
+
+// client is one socket connected to a board
+type client struct {
+	hub     *Hub
+	conn    *websocket.Conn
+	send    chan []byte
+	boardID int64
+	perm    string     // board.Permission* level the socket was opened with
+	actor   card.Actor // who mutations are attributed to
+
+	sessionID string // presence session, empty when presence is disabled
+
+	db *sql.DB
+}
+
+// isGuest reports whether the socket was opened through a share link
+func (c *client) isGuest() bool {
+	return c.actor.ShareID != nil
+}
+
+func (c *client) canEdit() bool {
+	return c.perm == board.PermissionOwner || c.perm == board.PermissionEdit
+}
+
+// sendJSON queues a message for this client only
+func (c *client) sendJSON(v interface{}) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if _, ok := c.hub.rooms[c.boardID][c]; !ok {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+	}
+}
+
+func (c *client) readPump() {
+	defer func() {
+		c.hub.leave(c)
+		c.hub.leavePresence(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg inboundMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendJSON(errorMessage{Type: "error", Error: "Invalid message"})
+			continue
+		}
+		c.handle(msg)
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// Hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
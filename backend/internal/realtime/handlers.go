@@ -0,0 +1,165 @@
+package realtime
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/presence"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+	"github.com/gorilla/websocket"
+)
+
+type BoardSocketHandler struct {
+	DB  *sql.DB
+	Hub *Hub
+}
+
+type ShareSocketHandler struct {
+	DB  *sql.DB
+	Hub *Hub
+}
+
+const maxGuestName = 64
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// checkOrigin mirrors the CORS middleware: same-origin, or CORS_ORIGIN when set
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	allowed := os.Getenv("CORS_ORIGIN")
+	if origin == "" || allowed == "*" {
+		return true
+	}
+	if allowed != "" && origin == allowed {
+		return true
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://") == r.Host
+}
+
+// GET /boards/{id}/ws
+// perms: any access level; only owner/edit may send mutations
+func (h *BoardSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /boards/{id}/ws
+	if len(parts) < 4 || parts[1] != "boards" || parts[3] != "ws" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	boardID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		middleware.JSONError(w, "Invalid board ID", http.StatusBadRequest)
+		return
+	}
+
+	perm, err := board.GetUserPermission(h.DB, userID, boardID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already wrote the HTTP error
+		log.Printf("WARN: websocket upgrade failed: %v", err)
+		return
+	}
+	// Name the participant after the account for other members; share-link
+	// sockets only ever see a generic label (see anonymous)
+	name, err := user.GetEmailByUserID(h.DB, userID)
+	if err != nil {
+		name = "User " + strconv.FormatInt(userID, 10)
+	}
+
+	h.Hub.serve(&client{
+		conn:    conn,
+		boardID: boardID,
+		perm:    perm,
+		actor:   card.UserActor(userID),
+		db:      h.DB,
+	}, presence.Participant{Kind: presence.KindUser, UserID: userID, Name: name})
+}
+
+// GET /share/{token}/ws
+// perms: read or edit link; only edit links may send mutations
+func (h *ShareSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /share/{token}/ws
+	if len(parts) < 4 || parts[1] != "share" || parts[3] != "ws" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	token := parts[2]
+
+	shareID, boardID, perm, err := board.GetShareLink(h.DB, token)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check share link", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Invalid or expired share link", http.StatusForbidden)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WARN: websocket upgrade failed: %v", err)
+		return
+	}
+	// Guests are anonymous; let the client pick a display name (?name=)
+	name := strings.TrimSpace(strings.ToValidUTF8(r.URL.Query().Get("name"), ""))
+	if runes := []rune(name); len(runes) > maxGuestName {
+		name = strings.TrimSpace(string(runes[:maxGuestName]))
+	}
+	if name == "" {
+		name = "Guest"
+	}
+
+	h.Hub.serve(&client{
+		conn:    conn,
+		boardID: boardID,
+		perm:    perm,
+		actor:   card.ShareActor(shareID),
+		db:      h.DB,
+	}, presence.Participant{Kind: presence.KindGuest, Name: name})
+}
+
+// serve registers the client and runs its pumps until the socket closes
+func (h *Hub) serve(c *client, p presence.Participant) {
+	c.hub = h
+	c.send = make(chan []byte, sendBuffer)
+	h.join(c)
+	h.joinPresence(c, p)
+
+	go c.writePump()
+	c.readPump()
+}
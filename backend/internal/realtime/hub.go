@@ -0,0 +1,106 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/presence"
+)
+
+// Hub fans card events out to every socket connected to the same board.
+// It is in-process only: each API instance broadcasts to its own clients.
+type Hub struct {
+	mu       sync.RWMutex
+	rooms    map[int64]map[*client]struct{}
+	presence *presence.Tracker
+}
+
+func NewHub(tracker *presence.Tracker) *Hub {
+	return &Hub{
+		rooms:    make(map[int64]map[*client]struct{}),
+		presence: tracker,
+	}
+}
+
+func (h *Hub) join(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[c.boardID]
+	if !ok {
+		room = make(map[*client]struct{})
+		h.rooms[c.boardID] = room
+	}
+	room[c] = struct{}{}
+}
+
+func (h *Hub) leave(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[c.boardID]
+	if !ok {
+		return
+	}
+	if _, ok := room[c]; ok {
+		delete(room, c)
+		close(c.send)
+	}
+	if len(room) == 0 {
+		delete(h.rooms, c.boardID)
+	}
+}
+
+// Publish implements card.Publisher
+func (h *Hub) Publish(ev card.Event) {
+	msg, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("WARN: failed to encode %s event: %v", ev.Type, err)
+		return
+	}
+	h.Broadcast(ev.BoardID, msg)
+}
+
+// broadcastJSON encodes v and sends it to every client on a board except one
+func (h *Hub) broadcastJSON(boardID int64, v interface{}, except *client) {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	h.broadcast(boardID, msg, except)
+}
+
+// Broadcast sends a raw message to every client on a board
+func (h *Hub) Broadcast(boardID int64, msg []byte) {
+	h.broadcast(boardID, msg, nil)
+}
+
+func (h *Hub) broadcast(boardID int64, msg []byte, except *client) {
+	h.broadcastEach(boardID, except, func(*client) []byte { return msg })
+}
+
+// broadcastEach sends every client on a board except one the message msgFor
+// picks for it
+func (h *Hub) broadcastEach(boardID int64, except *client, msgFor func(c *client) []byte) {
+	var slow []*client
+
+	h.mu.RLock()
+	for c := range h.rooms[boardID] {
+		if c == except {
+			continue
+		}
+		select {
+		case c.send <- msgFor(c):
+		default:
+			// Client can't keep up; drop it rather than block the board
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		h.leave(c)
+	}
+}
@@ -0,0 +1,213 @@
+package realtime
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+)
+
+// Messages a client may send over the socket
+const (
+	msgCardCreate = "card.create"
+	msgCardUpdate = "card.update"
+	msgCardDelete = "card.delete"
+)
+
+// errHandled signals that a reply has already been sent to the client
+const errHandled = "handled"
+
+type inboundMessage struct {
+	Type string `json:"type"`
+	Ref  string `json:"ref,omitempty"` // echoed back in the ack/error so clients can match replies
+
+	ID        int64    `json:"id,omitempty"`
+	Kind      string   `json:"kind,omitempty"` // create only: "text" | "image"
+	Text      *string  `json:"text,omitempty"`
+	ImageURL  string   `json:"image_url,omitempty"`
+	PositionX *float64 `json:"position_x,omitempty"`
+	PositionY *float64 `json:"position_y,omitempty"`
+	Width     *float64 `json:"width,omitempty"`   // images only
+	Height    *float64 `json:"height,omitempty"`  // images only
+	Version   int64    `json:"version,omitempty"` // update only: expected version, 0 = unconditional
+
+	X       *float64 `json:"x,omitempty"`        // cursor only
+	Y       *float64 `json:"y,omitempty"`        // cursor only
+	CardIDs []int64  `json:"card_ids,omitempty"` // selection only
+}
+
+type ackMessage struct {
+	Type   string `json:"type"`
+	Ref    string `json:"ref,omitempty"`
+	CardID int64  `json:"card_id"`
+}
+
+// conflictMessage answers a stale update with the card as the server has it
+type conflictMessage struct {
+	Type  string    `json:"type"`
+	Ref   string    `json:"ref,omitempty"`
+	Error string    `json:"error"`
+	Card  card.Card `json:"card"`
+}
+
+type errorMessage struct {
+	Type  string `json:"type"`
+	Ref   string `json:"ref,omitempty"`
+	Error string `json:"error"`
+}
+
+// handle applies a mutation sent over the socket. The resulting card event is
+// broadcast to the whole board (sender included) by the card model functions.
+func (c *client) handle(msg inboundMessage) {
+	switch msg.Type {
+	case msgCardCreate, msgCardUpdate, msgCardDelete:
+	case msgCursor, msgSelection:
+		c.handlePresence(msg)
+		return
+	default:
+		c.fail(msg, "Unknown message type")
+		return
+	}
+
+	if !c.canEdit() {
+		c.fail(msg, "Forbidden")
+		return
+	}
+
+	var (
+		cardID int64
+		errMsg string
+	)
+	switch msg.Type {
+	case msgCardCreate:
+		cardID, errMsg = c.createCard(msg)
+	case msgCardUpdate:
+		cardID, errMsg = c.updateCard(msg)
+	case msgCardDelete:
+		cardID, errMsg = c.deleteCard(msg)
+	}
+	if errMsg == errHandled {
+		return
+	}
+	if errMsg != "" {
+		c.fail(msg, errMsg)
+		return
+	}
+	c.sendJSON(ackMessage{Type: "ack", Ref: msg.Ref, CardID: cardID})
+}
+
+func (c *client) fail(msg inboundMessage, errMsg string) {
+	c.sendJSON(errorMessage{Type: "error", Ref: msg.Ref, Error: errMsg})
+}
+
+func (c *client) createCard(msg inboundMessage) (int64, string) {
+	var x, y float64
+	if msg.PositionX != nil {
+		x = *msg.PositionX
+	}
+	if msg.PositionY != nil {
+		y = *msg.PositionY
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(msg.Kind))
+	if kind == "" {
+		kind = "text"
+	}
+
+	switch kind {
+	case "text":
+		text := ""
+		if msg.Text != nil {
+			text = *msg.Text
+		}
+		id, err := card.Revise(c.db, c.actor, card.RevisionCreate, 0, func(tx *card.Tx) (int64, error) {
+			return card.CreateCard(tx, c.boardID, text, x, y)
+		})
+		if err != nil {
+			return 0, "Failed to create card"
+		}
+		return id, ""
+
+	case "image":
+		if strings.TrimSpace(msg.ImageURL) == "" {
+			return 0, "image_url is required for kind=image"
+		}
+		id, err := card.Revise(c.db, c.actor, card.RevisionCreate, 0, func(tx *card.Tx) (int64, error) {
+			return card.CreateImageCard(tx, c.boardID, msg.ImageURL, x, y, msg.Width, msg.Height)
+		})
+		if err == card.ErrImageNotConfirmed {
+			return 0, err.Error()
+		}
+		if err != nil {
+			return 0, "Failed to create image card"
+		}
+		return id, ""
+
+	default:
+		return 0, "invalid kind (must be 'text' or 'image')"
+	}
+}
+
+// loadCard fetches a card and makes sure it belongs to this socket's board
+func (c *client) loadCard(cardID int64) (card.Card, string) {
+	existing, err := card.GetCard(c.db, cardID)
+	if err == sql.ErrNoRows || (err == nil && existing.BoardID != c.boardID) {
+		return card.Card{}, "Card not found"
+	}
+	if err != nil {
+		return card.Card{}, "Failed to fetch card"
+	}
+	return existing, ""
+}
+
+func (c *client) updateCard(msg inboundMessage) (int64, string) {
+	existing, errMsg := c.loadCard(msg.ID)
+	if errMsg != "" {
+		return 0, errMsg
+	}
+
+	// Omitted fields keep their current values
+	patch := card.Patch{
+		Text:      msg.Text,
+		PositionX: msg.PositionX,
+		PositionY: msg.PositionY,
+		Width:     msg.Width,
+		Height:    msg.Height,
+	}
+	_, err := card.Revise(c.db, c.actor, card.RevisionUpdate, existing.ID, func(tx *card.Tx) (int64, error) {
+		return card.PatchCard(tx, existing, patch, msg.Version)
+	})
+	if err == card.ErrVersionConflict {
+		return 0, c.conflict(msg, existing.ID)
+	}
+	if err != nil {
+		return 0, "Failed to update card"
+	}
+	return existing.ID, ""
+}
+
+// conflict sends the current card to the client; the returned error message
+// is used when the card could not be reloaded
+func (c *client) conflict(msg inboundMessage, cardID int64) string {
+	current, err := card.GetCard(c.db, cardID)
+	if err != nil {
+		return card.ErrVersionConflict.Error()
+	}
+	c.sendJSON(conflictMessage{Type: "conflict", Ref: msg.Ref, Error: card.ErrVersionConflict.Error(), Card: current})
+	return errHandled
+}
+
+func (c *client) deleteCard(msg inboundMessage) (int64, string) {
+	existing, errMsg := c.loadCard(msg.ID)
+	if errMsg != "" {
+		return 0, errMsg
+	}
+
+	_, err := card.Revise(c.db, c.actor, card.RevisionDelete, existing.ID, func(tx *card.Tx) (int64, error) {
+		return card.DeleteCard(tx, existing.ID)
+	})
+	if err != nil {
+		return 0, "Failed to delete card"
+	}
+	return existing.ID, ""
+}
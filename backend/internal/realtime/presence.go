@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"encoding/json"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/presence"
+)
+
+// Ephemeral presence messages. These are relayed between sockets and kept in
+// the presence.Tracker only; nothing here touches the database.
+const (
+	msgCursor    = "cursor"    // inbound: {x, y}
+	msgSelection = "selection" // inbound: {card_ids}
+
+	msgPresenceSync      = "presence.sync"
+	msgPresenceJoin      = "presence.join"
+	msgPresenceLeave     = "presence.leave"
+	msgPresenceCursor    = "presence.cursor"
+	msgPresenceSelection = "presence.selection"
+)
+
+type presenceSyncMessage struct {
+	Type         string                 `json:"type"`
+	SessionID    string                 `json:"session_id"` // the receiving socket's own session
+	Participants []presence.Participant `json:"participants"`
+}
+
+type presenceJoinMessage struct {
+	Type        string               `json:"type"`
+	Participant presence.Participant `json:"participant"`
+}
+
+type presenceLeaveMessage struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+}
+
+type presenceCursorMessage struct {
+	Type      string  `json:"type"`
+	SessionID string  `json:"session_id"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+}
+
+type presenceSelectionMessage struct {
+	Type      string  `json:"type"`
+	SessionID string  `json:"session_id"`
+	CardIDs   []int64 `json:"card_ids"`
+}
+
+// memberLabel replaces signed-in participants' names for share-link
+// sockets, which must not learn who the board's members are
+const memberLabel = "Board member"
+
+// joinPresence registers the client as a participant and announces it
+func (h *Hub) joinPresence(c *client, p presence.Participant) {
+	if h.presence == nil {
+		return
+	}
+	p.Permission = c.perm
+	p = h.presence.Join(c.boardID, p)
+	c.sessionID = p.SessionID
+
+	participants := h.presence.List(c.boardID)
+	if c.isGuest() {
+		for i := range participants {
+			participants[i] = anonymous(participants[i])
+		}
+	}
+	c.sendJSON(presenceSyncMessage{
+		Type:         msgPresenceSync,
+		SessionID:    p.SessionID,
+		Participants: participants,
+	})
+
+	full, err := json.Marshal(presenceJoinMessage{Type: msgPresenceJoin, Participant: p})
+	if err != nil {
+		return
+	}
+	redacted, err := json.Marshal(presenceJoinMessage{Type: msgPresenceJoin, Participant: anonymous(p)})
+	if err != nil {
+		return
+	}
+	h.broadcastEach(c.boardID, c, func(to *client) []byte {
+		if to.isGuest() {
+			return redacted
+		}
+		return full
+	})
+}
+
+// anonymous hides who a signed-in participant is; guests are left as they
+// named themselves
+func anonymous(p presence.Participant) presence.Participant {
+	if p.Kind == presence.KindUser {
+		p.UserID = 0
+		p.Name = memberLabel
+	}
+	return p
+}
+
+func (h *Hub) leavePresence(c *client) {
+	if h.presence == nil || c.sessionID == "" {
+		return
+	}
+	h.presence.Leave(c.boardID, c.sessionID)
+	h.broadcastJSON(c.boardID, presenceLeaveMessage{Type: msgPresenceLeave, SessionID: c.sessionID}, c)
+}
+
+// handlePresence relays cursor and selection updates; any permission level may send them
+func (c *client) handlePresence(msg inboundMessage) {
+	if c.hub.presence == nil || c.sessionID == "" {
+		return
+	}
+
+	switch msg.Type {
+	case msgCursor:
+		if msg.X == nil || msg.Y == nil {
+			c.fail(msg, "x and y are required")
+			return
+		}
+		if !c.hub.presence.SetCursor(c.boardID, c.sessionID, *msg.X, *msg.Y) {
+			return
+		}
+		c.hub.broadcastJSON(c.boardID, presenceCursorMessage{
+			Type:      msgPresenceCursor,
+			SessionID: c.sessionID,
+			X:         *msg.X,
+			Y:         *msg.Y,
+		}, c)
+
+	case msgSelection:
+		ids := msg.CardIDs
+		if ids == nil {
+			ids = []int64{}
+		}
+		if !c.hub.presence.SetSelection(c.boardID, c.sessionID, ids) {
+			return
+		}
+		c.hub.broadcastJSON(c.boardID, presenceSelectionMessage{
+			Type:      msgPresenceSelection,
+			SessionID: c.sessionID,
+			CardIDs:   ids,
+		}, c)
+	}
+}
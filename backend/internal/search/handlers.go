@@ -0,0 +1,72 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/user"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	maxQuery     = 200
+)
+
+type Handler struct {
+	DB *sql.DB
+}
+
+// GET /search?q=
+// Searches every board the caller owns or has been given access to.
+//
+// Query params:
+//   - q: words to look for (required)
+//   - limit: most boards and most cards to return (default 20, max 100)
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := user.GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		middleware.JSONError(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	if len(q) > maxQuery {
+		middleware.JSONError(w, "q is too long", http.StatusBadRequest)
+		return
+	}
+	limit := defaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			middleware.JSONError(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if n > maxLimit {
+			n = maxLimit
+		}
+		limit = n
+	}
+
+	res, err := Search(h.DB, userID, q, limit)
+	if err != nil {
+		log.Printf("search for user %d failed: %v", userID, err)
+		middleware.JSONError(w, "Failed to search", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,195 @@
+// Package search finds cards and boards by their text across every board a
+// user can open, using the FULLTEXT indexes on cards.text and boards.title.
+package search
+
+import (
+	"database/sql"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// minTermLength matches InnoDB's default innodb_ft_min_token_size; shorter
+// words are not in the index, so queries made only of them fall back to LIKE
+const minTermLength = 3
+
+// snippetRadius is how much text a snippet shows either side of the first match
+const snippetRadius = 60
+
+// BoardResult is a board whose title matched
+type BoardResult struct {
+	BoardID int64   `json:"board_id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"` // HTML-escaped title with matches in <mark>
+	Score   float64 `json:"score"`
+}
+
+// CardResult is a card whose text matched, with where it sits so the UI can
+// jump to it
+type CardResult struct {
+	BoardID    int64   `json:"board_id"`
+	BoardTitle string  `json:"board_title"`
+	CardID     int64   `json:"card_id"`
+	Snippet    string  `json:"snippet"` // HTML-escaped excerpt with matches in <mark>
+	PositionX  float64 `json:"position_x"`
+	PositionY  float64 `json:"position_y"`
+	Score      float64 `json:"score"`
+}
+
+// Results is everything that matched a query
+type Results struct {
+	Query  string        `json:"query"`
+	Boards []BoardResult `json:"boards"`
+	Cards  []CardResult  `json:"cards"`
+}
+
+// visible keeps the boards a user can open, the ones board.GetBoards lists:
+// those they own and those shared with them. Takes the user's ID twice.
+const visible = "(b.owner_id = ? OR EXISTS (SELECT 1 FROM board_access ba WHERE ba.board_id = b.id AND ba.user_id = ?))"
+
+// Search looks for q in the titles and card text of every board userID can
+// open, best matches first, at most limit of each. Words match as prefixes,
+// so "onboard" finds "onboarding".
+func Search(db *sql.DB, userID int64, q string, limit int) (Results, error) {
+	res := Results{Query: q, Boards: []BoardResult{}, Cards: []CardResult{}}
+	terms := Terms(q)
+	if len(terms) == 0 {
+		return res, nil
+	}
+
+	// MATCH when the index can answer; LIKE, unranked, for very short words
+	where, score := "MATCH(col) AGAINST (? IN BOOLEAN MODE)", "MATCH(col) AGAINST (? IN BOOLEAN MODE)"
+	arg := booleanQuery(terms)
+	scoreArgs := []interface{}{arg}
+	if arg == "" {
+		where, score = "col LIKE ?", "0"
+		arg = "%" + escapeLike(strings.TrimSpace(q)) + "%"
+		scoreArgs = nil
+	}
+	args := append(scoreArgs, userID, userID, arg, limit)
+
+	rows, err := db.Query(
+		"SELECT b.id, b.title, "+on(score, "b.title")+" AS score FROM boards b WHERE "+visible+" AND "+on(where, "b.title")+" ORDER BY score DESC, b.id LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b BoardResult
+		if err := rows.Scan(&b.BoardID, &b.Title, &b.Score); err != nil {
+			return res, err
+		}
+		b.Snippet = Snippet(b.Title, terms)
+		res.Boards = append(res.Boards, b)
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	rows.Close()
+
+	rows, err = db.Query(
+		"SELECT c.id, c.board_id, b.title, c.text, c.position_x, c.position_y, "+on(score, "c.text")+" AS score FROM cards c JOIN boards b ON b.id = c.board_id WHERE "+visible+" AND "+on(where, "c.text")+" ORDER BY score DESC, c.id LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c CardResult
+		var text string
+		if err := rows.Scan(&c.CardID, &c.BoardID, &c.BoardTitle, &text, &c.PositionX, &c.PositionY, &c.Score); err != nil {
+			return res, err
+		}
+		c.Snippet = Snippet(text, terms)
+		res.Cards = append(res.Cards, c)
+	}
+	return res, rows.Err()
+}
+
+// on fills in the column of a condition or score expression
+func on(expr, column string) string {
+	return strings.ReplaceAll(expr, "col", column)
+}
+
+// Terms splits a query into lower-case words, dropping punctuation and the
+// operators boolean mode would otherwise interpret
+func Terms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// booleanQuery ORs together a prefix search for every indexable term
+func booleanQuery(terms []string) string {
+	var parts []string
+	for _, t := range terms {
+		if len([]rune(t)) >= minTermLength {
+			parts = append(parts, t+"*")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Snippet cuts text down to the part around the first match of any term and
+// marks every word starting with a term. The result is HTML-escaped.
+func Snippet(text string, terms []string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Words that begin with a term, as [start, end) rune ranges
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(lower); i++ {
+		if i > 0 && (unicode.IsLetter(lower[i-1]) || unicode.IsDigit(lower[i-1])) {
+			continue
+		}
+		for _, t := range terms {
+			tr := []rune(t)
+			if i+len(tr) <= len(lower) && string(lower[i:i+len(tr)]) == t {
+				end := i + len(tr)
+				for end < len(lower) && (unicode.IsLetter(lower[end]) || unicode.IsDigit(lower[end])) {
+					end++
+				}
+				spans = append(spans, span{i, end})
+				break
+			}
+		}
+	}
+
+	from, to := 0, len(runes)
+	if len(spans) > 0 {
+		from = max(0, spans[0].start-snippetRadius)
+		to = min(len(runes), spans[0].end+snippetRadius)
+	} else {
+		to = min(len(runes), 2*snippetRadius)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.start < from || s.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:s.start])))
+		b.WriteString("<mark>" + html.EscapeString(string(runes[s.start:s.end])) + "</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
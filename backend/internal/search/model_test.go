@@ -0,0 +1,93 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"Hello, WORLD!", []string{"hello", "world"}},
+		{`+plan -"road map" onboard*`, []string{"plan", "road", "map", "onboard"}},
+		{"Café-Über 2024", []string{"café", "über", "2024"}},
+		{` +-*"()~<> `, nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := Terms(tt.q)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestBooleanQuery(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{[]string{"plan", "road"}, "plan* road*"},
+		{[]string{"to", "plan", "ab"}, "plan*"},
+		{[]string{"wör"}, "wör*"}, // counted in characters, not bytes
+		{[]string{"ab", "c"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := booleanQuery(tt.terms); got != tt.want {
+			t.Errorf("booleanQuery(%q) = %q, want %q", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"prefix match", "Plan the onboarding flow", []string{"onboard"}, "Plan the <mark>onboarding</mark> flow"},
+		{"case kept", "ONBOARDING", []string{"onboard"}, "<mark>ONBOARDING</mark>"},
+		{"inside a word", "custom tomato", []string{"tom"}, "custom <mark>tomato</mark>"},
+		{"every match", "road map, roadmap", []string{"road"}, "<mark>road</mark> map, <mark>roadmap</mark>"},
+		{"html escaped", "<b>Tom & Jerry</b>", []string{"tom"}, "&lt;b&gt;<mark>Tom</mark> &amp; Jerry&lt;/b&gt;"},
+		{"markup in text", "a<mark>", []string{"mark"}, "a&lt;<mark>mark</mark>&gt;"},
+		{"quotes escaped", `say "hi"`, []string{"hi"}, "say &#34;<mark>hi</mark>&#34;"},
+		{"whitespace collapsed", "one\n\n  two", []string{"two"}, "one <mark>two</mark>"},
+		{"no match", "nothing here", []string{"zzz"}, "nothing here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms); got != tt.want {
+				t.Errorf("Snippet(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetCutsAroundFirstMatch(t *testing.T) {
+	text := strings.Repeat("x ", 100) + "needle " + strings.Repeat("y ", 100)
+	got := Snippet(text, []string{"needle"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet %q is not cut on both sides", got)
+	}
+	if !strings.Contains(got, " <mark>needle</mark> ") {
+		t.Errorf("snippet %q does not mark the match", got)
+	}
+	if n, want := utf8.RuneCountInString(got), 2*snippetRadius+len("<mark>needle</mark>")+2; n != want {
+		t.Errorf("snippet is %d characters, want %d", n, want)
+	}
+
+	long := strings.Repeat("a", 200)
+	if got, want := Snippet(long, []string{"zzz"}), strings.Repeat("a", 2*snippetRadius)+"…"; got != want {
+		t.Errorf("snippet without a match = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package share
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+)
+
+type ShareBatchHandler struct {
+	DB *sql.DB
+}
+
+// POST /share/{token}/cards:batch
+// perms: edit only
+func (h *ShareBatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	// /share/{token}/cards:batch
+	if len(parts) < 4 || parts[1] != "share" || parts[3] != "cards:batch" {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	token := parts[2]
+
+	shareID, boardID, perm, err := board.GetShareLink(h.DB, token)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check share link", http.StatusInternalServerError)
+		return
+	}
+	if perm != board.PermissionEdit {
+		middleware.JSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	card.ServeBatch(w, r, h.DB, boardID, card.ShareActor(shareID))
+}
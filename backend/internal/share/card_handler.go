@@ -1,10 +1,8 @@
 package share
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,14 +10,10 @@ import (
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
 	"github.com/LoganTackett1/brainstorming-backend/internal/card"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
-
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 type ShareCardHandler struct {
-	DB       *sql.DB
-	S3Client *s3.Client
-	Bucket   string
+	DB *sql.DB
 }
 
 type createShareCardReq struct {
@@ -36,12 +30,13 @@ type updateShareCardReq struct {
 	Text      *string  `json:"text,omitempty"`
 	PositionX *float64 `json:"position_x,omitempty"`
 	PositionY *float64 `json:"position_y,omitempty"`
-	Width     *float64 `json:"width,omitempty"`  // images only
-	Height    *float64 `json:"height,omitempty"` // images only
+	Width     *float64 `json:"width,omitempty"`   // images only
+	Height    *float64 `json:"height,omitempty"`  // images only
+	Version   *int64   `json:"version,omitempty"` // alternative to If-Match
 }
 
 // Handles:
-// - GET    /share/{token}/cards
+// - GET    /share/{token}/cards[?since=<cursor>]
 // - POST   /share/{token}/cards
 // - PUT    /share/{token}/cards/{id}
 // - DELETE /share/{token}/cards/{id}
@@ -54,7 +49,7 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	token := parts[2]
 
-	boardID, perm, err := board.GetSharePermission(h.DB, token)
+	shareID, boardID, perm, err := board.GetShareLink(h.DB, token)
 	if err != nil {
 		middleware.JSONError(w, "Failed to check share link", http.StatusInternalServerError)
 		return
@@ -63,17 +58,14 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		middleware.JSONError(w, "Invalid or expired share link", http.StatusForbidden)
 		return
 	}
+	actor := card.ShareActor(shareID) // changes are attributed to the link
 
 	// --- Subroute: /share/{token}/cards ---
 	if len(parts) == 4 && parts[3] == "cards" {
 		switch r.Method {
 		case http.MethodGet:
-			cards, err := card.GetCardsByBoard(h.DB, boardID)
-			if err != nil {
-				middleware.JSONError(w, "Failed to fetch cards", http.StatusInternalServerError)
-				return
-			}
-			json.NewEncoder(w).Encode(cards)
+			// Full list, or only what changed with ?since=<cursor>
+			card.WriteCardList(w, r, h.DB, boardID)
 
 		case http.MethodPost:
 			if perm != board.PermissionEdit {
@@ -98,7 +90,9 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				if strings.TrimSpace(body.Text) == "" {
 					body.Text = ""
 				}
-				id, err := card.CreateCard(h.DB, boardID, body.Text, body.PositionX, body.PositionY)
+				id, err := card.Revise(h.DB, actor, card.RevisionCreate, 0, func(tx *card.Tx) (int64, error) {
+					return card.CreateCard(tx, boardID, body.Text, body.PositionX, body.PositionY)
+				})
 				if err != nil {
 					middleware.JSONError(w, "Failed to create card", http.StatusInternalServerError)
 					return
@@ -110,6 +104,7 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					"text":       body.Text,
 					"position_x": body.PositionX,
 					"position_y": body.PositionY,
+					"version":    1,
 				})
 
 			case "image":
@@ -117,7 +112,13 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					middleware.JSONError(w, "image_url is required for kind=image", http.StatusBadRequest)
 					return
 				}
-				id, err := card.CreateImageCard(h.DB, boardID, body.ImageURL, body.PositionX, body.PositionY, body.Width, body.Height)
+				id, err := card.Revise(h.DB, actor, card.RevisionCreate, 0, func(tx *card.Tx) (int64, error) {
+					return card.CreateImageCard(tx, boardID, body.ImageURL, body.PositionX, body.PositionY, body.Width, body.Height)
+				})
+				if err == card.ErrImageNotConfirmed {
+					middleware.JSONError(w, err.Error(), http.StatusUnprocessableEntity)
+					return
+				}
 				if err != nil {
 					middleware.JSONError(w, "Failed to create image card", http.StatusInternalServerError)
 					return
@@ -131,6 +132,7 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					"position_y": body.PositionY,
 					"width":      body.Width,
 					"height":     body.Height,
+					"version":    1,
 				})
 
 			default:
@@ -179,6 +181,11 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				middleware.JSONError(w, "Invalid request body", http.StatusBadRequest)
 				return
 			}
+			version, err := card.ExpectedVersion(r, body.Version)
+			if err != nil {
+				middleware.JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			// Default current positions if not provided
 			var curX, curY float64
@@ -210,7 +217,13 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					txt = *body.Text
 				}
 
-				affected, err := card.UpdateCard(h.DB, cardID, txt, x, y)
+				affected, err := card.Revise(h.DB, actor, card.RevisionUpdate, cardID, func(tx *card.Tx) (int64, error) {
+					return card.UpdateCard(tx, cardID, txt, x, y, version)
+				})
+				if err == card.ErrVersionConflict {
+					card.WriteConflict(w, h.DB, cardID)
+					return
+				}
 				if err != nil {
 					middleware.JSONError(w, "Failed to update card", http.StatusInternalServerError)
 					return
@@ -219,7 +232,7 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					json.NewEncoder(w).Encode(map[string]string{"status": "no card found"})
 					return
 				}
-				json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+				card.WriteUpdated(w, h.DB, cardID)
 
 			case "image":
 				// Preserve existing width/height when omitted (prevents resetting to NULL)
@@ -243,7 +256,13 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					hPtr = &val
 				}
 
-				affected, err := card.UpdateImageCard(h.DB, cardID, x, y, wPtr, hPtr)
+				affected, err := card.Revise(h.DB, actor, card.RevisionUpdate, cardID, func(tx *card.Tx) (int64, error) {
+					return card.UpdateImageCard(tx, cardID, x, y, wPtr, hPtr, version)
+				})
+				if err == card.ErrVersionConflict {
+					card.WriteConflict(w, h.DB, cardID)
+					return
+				}
 				if err != nil {
 					middleware.JSONError(w, "Failed to update image card", http.StatusInternalServerError)
 					return
@@ -252,17 +271,17 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					json.NewEncoder(w).Encode(map[string]string{"status": "no card found"})
 					return
 				}
-				json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+				card.WriteUpdated(w, h.DB, cardID)
 
 			default:
 				middleware.JSONError(w, "invalid card kind", http.StatusBadRequest)
 			}
 
 		case http.MethodDelete:
-			// 1) Look up info for S3 cleanup (ensure card belongs to this share's board)
-			var kind, imageURL string
-			if err := h.DB.QueryRow("SELECT kind, COALESCE(image_url, '') FROM cards WHERE id = ? AND board_id = ?", cardID, boardID).
-				Scan(&kind, &imageURL); err != nil {
+			// 1) Ensure the card belongs to this share's board
+			var exists int
+			if err := h.DB.QueryRow("SELECT 1 FROM cards WHERE id = ? AND board_id = ?", cardID, boardID).
+				Scan(&exists); err != nil {
 				if err == sql.ErrNoRows {
 					json.NewEncoder(w).Encode(map[string]string{"status": "no card found"})
 					return
@@ -271,22 +290,10 @@ func (h *ShareCardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			// 2) If image card, delete S3 object (best-effort)
-			if kind == "image" && imageURL != "" && h.S3Client != nil && h.Bucket != "" {
-				if idx := strings.LastIndex(imageURL, "images/"); idx != -1 {
-					s3Key := imageURL[idx:] // e.g. "images/<boardID>/<uuid>.jpg"
-					_, err := h.S3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-						Bucket: &h.Bucket,
-						Key:    &s3Key,
-					})
-					if err != nil {
-						log.Printf("WARN: failed to delete S3 object %s: %v", s3Key, err)
-					}
-				}
-			}
-
-			// 3) Delete DB row
-			affected, err := card.DeleteCard(h.DB, cardID)
+			// 2) Delete DB row; stored images stay behind for undo and restores
+			affected, err := card.Revise(h.DB, actor, card.RevisionDelete, cardID, func(tx *card.Tx) (int64, error) {
+				return card.DeleteCard(tx, cardID)
+			})
 			if err != nil {
 				middleware.JSONError(w, "Failed to delete card", http.StatusInternalServerError)
 				return
@@ -0,0 +1,41 @@
+package share
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+type ShareExportHandler struct {
+	DB    *sql.DB
+	Store storage.Store
+}
+
+// GET /share/{token}/export.{svg|png|pdf|json|md|opml|excalidraw}, see export.Serve
+// perms: read or edit
+func (h *ShareExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	// /share/{token}/export.{format}
+	if len(parts) != 4 || parts[1] != "share" || !strings.HasPrefix(parts[3], "export.") {
+		middleware.JSONError(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	token := parts[2]
+
+	boardID, perm, err := board.GetSharePermission(h.DB, token)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check share link", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Invalid or expired share link", http.StatusForbidden)
+		return
+	}
+
+	export.Serve(w, r, h.DB, h.Store, boardID, strings.TrimPrefix(parts[3], "export."))
+}
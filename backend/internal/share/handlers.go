@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/LoganTackett1/brainstorming-backend/internal/activity"
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
 	"github.com/LoganTackett1/brainstorming-backend/internal/user"
@@ -82,6 +83,10 @@ func (h *ShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Failed to create share link", http.StatusInternalServerError)
 			return
 		}
+		activity.Log(h.DB, boardID, &userID, nil, activity.TypeShareCreated, map[string]interface{}{
+			"share_id":   share.ID,
+			"permission": share.Permission,
+		})
 		json.NewEncoder(w).Encode(share)
 
 	case http.MethodDelete:
@@ -93,7 +98,7 @@ func (h *ShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		affected, err := DeleteShare(h.DB, body.ShareID)
+		affected, err := DeleteShare(h.DB, boardID, body.ShareID)
 		if err != nil {
 			middleware.JSONError(w, "Failed to delete share link", http.StatusInternalServerError)
 			return
@@ -102,6 +107,9 @@ func (h *ShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			middleware.JSONError(w, "Share link not found", http.StatusNotFound)
 			return
 		}
+		activity.Log(h.DB, boardID, &userID, nil, activity.TypeShareDeleted, map[string]interface{}{
+			"share_id": body.ShareID,
+		})
 		json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
 
 	default:
@@ -1,38 +1,32 @@
 package share
 
 import (
-	"context"
 	"database/sql"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/LoganTackett1/brainstorming-backend/internal/awsclient"
 	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
-	"github.com/google/uuid"
-
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
 )
 
 type ShareImageUploadHandler struct {
-	DB     *sql.DB
-	S3     *s3.Client
-	Bucket string
+	DB    *sql.DB
+	Store storage.Store
 }
 
-func NewShareImageUploadHandler(db *sql.DB) *ShareImageUploadHandler {
+func NewShareImageUploadHandler(db *sql.DB, store storage.Store) *ShareImageUploadHandler {
 	return &ShareImageUploadHandler{
-		DB:     db,
-		S3:     awsclient.NewS3Client(),
-		Bucket: os.Getenv("S3_BUCKET"),
+		DB:    db,
+		Store: store,
 	}
 }
 
-// POST /share/{token}/images  (multipart form: file=<file>)
+// Routes handled:
+// - POST /share/{token}/images           (multipart form: file=<file>)
+// - POST /share/{token}/images/presign   direct upload to storage, see card.ServePresign
+// - POST /share/{token}/images/confirm   finish a direct upload, see card.ServeConfirm
 // perms: edit only
 func (h *ShareImageUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -48,7 +42,7 @@ func (h *ShareImageUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	}
 	token := parts[2]
 
-	boardID, perm, err := board.GetSharePermission(h.DB, token)
+	shareID, boardID, perm, err := board.GetShareLink(h.DB, token)
 	if err != nil {
 		middleware.JSONError(w, "Failed to check share link", http.StatusInternalServerError)
 		return
@@ -58,35 +52,18 @@ func (h *ShareImageUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		middleware.JSONError(w, "Invalid form data", http.StatusBadRequest)
-		return
-	}
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		middleware.JSONError(w, "Missing file", http.StatusBadRequest)
+	// /share/{token}/images/{presign|confirm}
+	if len(parts) == 5 {
+		switch parts[4] {
+		case "presign":
+			card.ServePresign(w, r, h.DB, h.Store, boardID, card.ShareActor(shareID))
+		case "confirm":
+			card.ServeConfirm(w, r, h.DB, h.Store, boardID)
+		default:
+			middleware.JSONError(w, "Not found", http.StatusNotFound)
+		}
 		return
 	}
-	defer file.Close()
-
-	ext := strings.ToLower(filepath.Ext(handler.Filename))
-	if ext == "" {
-		ext = ".bin"
-	}
-	key := fmt.Sprintf("images/%d/%s%s", boardID, uuid.New().String(), ext)
-
-	_, err = h.S3.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket:      &h.Bucket,
-		Key:         &key,
-		Body:        file,
-	})
-	if err != nil {
-		middleware.JSONError(w, "Failed to upload to S3", http.StatusInternalServerError)
-		return
-	}
-
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", h.Bucket, os.Getenv("AWS_REGION"), key)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"url": url})
+	card.ServeImageUpload(w, r, h.DB, h.Store, boardID, card.ShareActor(shareID))
 }
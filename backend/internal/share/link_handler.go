@@ -0,0 +1,46 @@
+package share
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+)
+
+type ShareLinkHandler struct {
+	DB *sql.DB
+}
+
+// Handles:
+// - GET    /share/{token}/links
+// - POST   /share/{token}/links
+// - PUT    /share/{token}/links/{id}
+// - DELETE /share/{token}/links/{id}
+func (h *ShareLinkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 || parts[1] != "share" || parts[3] != "links" {
+		middleware.JSONError(w, "Invalid share link", http.StatusBadRequest)
+		return
+	}
+	token := parts[2]
+
+	linkID, ok := card.ParseLinkID(w, parts)
+	if !ok {
+		return
+	}
+
+	boardID, perm, err := board.GetSharePermission(h.DB, token)
+	if err != nil {
+		middleware.JSONError(w, "Failed to check share link", http.StatusInternalServerError)
+		return
+	}
+	if perm == board.PermissionNone {
+		middleware.JSONError(w, "Invalid or expired share link", http.StatusForbidden)
+		return
+	}
+
+	card.ServeLinks(w, r, h.DB, boardID, linkID, perm == board.PermissionEdit)
+}
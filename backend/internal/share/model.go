@@ -77,9 +77,9 @@ func GetSharesByBoard(db *sql.DB, boardID int64) ([]BoardShare, error) {
 	return shares, nil
 }
 
-// Delete a share link
-func DeleteShare(db *sql.DB, shareID int64) (int64, error) {
-	res, err := db.Exec("DELETE FROM board_shares WHERE id = ?", shareID)
+// Delete one of a board's share links
+func DeleteShare(db *sql.DB, boardID, shareID int64) (int64, error) {
+	res, err := db.Exec("DELETE FROM board_shares WHERE id = ? AND board_id = ?", shareID, boardID)
 	if err != nil {
 		return 0, err
 	}
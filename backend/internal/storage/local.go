@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilesPrefix is the API path LocalStore URLs point at
+const FilesPrefix = "/files/"
+
+// LocalStore keeps objects on disk under Dir and serves them from the API
+// itself (see Handler), for dev, CI and self-hosted setups without AWS
+type LocalStore struct {
+	Dir     string
+	BaseURL string // public origin of the API, e.g. "http://localhost:8080"
+	Secret  []byte // signs SignedURL and PresignPut URLs
+}
+
+func NewLocalStore(dir, baseURL string, secret []byte) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/"), Secret: secret}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file first so readers never see partial objects
+func (s *LocalStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.BaseURL + FilesPrefix + key
+}
+
+// SignedURL returns a /files/ URL the Handler accepts until it expires
+func (s *LocalStore) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	if len(s.Secret) == 0 {
+		return "", errNoSecret
+	}
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+	q.Set("sig", s.sign(http.MethodGet, key, q))
+	return s.URL(key) + "?" + q.Encode(), nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (*Object, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+	return &Object{
+		Body: f,
+		Info: Info{
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+		},
+	}, nil
+}
+
+func (s *LocalStore) Stat(ctx context.Context, key string) (Info, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return Info{}, err
+	}
+	info, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	if info.IsDir() {
+		return Info{}, ErrNotFound
+	}
+	return Info{
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// List walks Dir in key order. Temporary files from in-progress Puts are
+// skipped.
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(key string, info Info) error) error {
+	return filepath.WalkDir(s.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			// Only descend into directories that can hold matching keys
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(key, Info{
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+		})
+	})
+}
+
+// PresignPut returns a URL back to this API's /files/ handler, signed so only
+// the exact key, type and size can be uploaded before it expires
+func (s *LocalStore) PresignPut(ctx context.Context, key, contentType string, size int64, ttl time.Duration) (PresignedPut, error) {
+	if !validKey(key) {
+		return PresignedPut{}, ErrInvalidKey
+	}
+	if size <= 0 {
+		return PresignedPut{}, errUploadSize
+	}
+	if len(s.Secret) == 0 {
+		return PresignedPut{}, errNoSecret
+	}
+	expires := time.Now().Add(ttl)
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("size", strconv.FormatInt(size, 10))
+	q.Set("ct", contentType)
+	q.Set("sig", s.sign(http.MethodPut, key, q))
+	return PresignedPut{
+		URL:       s.URL(key) + "?" + q.Encode(),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expires,
+	}, nil
+}
+
+var (
+	errNoSecret   = errors.New("local storage has no signing key (set STORAGE_SIGNING_KEY or JWT_SECRET)")
+	errUploadSize = errors.New("presigned uploads need a positive size")
+)
+
+// sign covers the method, key and every limit in the query, so none of them
+// can be changed without invalidating the URL
+func (s *LocalStore) sign(method, key string, q url.Values) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	io.WriteString(mac, strings.Join([]string{method, key, q.Get("expires"), q.Get("size"), q.Get("ct")}, "\n"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a signed URL's signature and expiry
+func (s *LocalStore) verify(method, key string, q url.Values) bool {
+	if len(s.Secret) == 0 || !hmac.Equal([]byte(q.Get("sig")), []byte(s.sign(method, key, q))) {
+		return false
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	return err == nil && time.Now().Unix() <= expires
+}
+
+// servePut accepts an upload made with a PresignPut URL
+func (s *LocalStore) servePut(w http.ResponseWriter, r *http.Request, key string) {
+	q := r.URL.Query()
+	if !s.verify(http.MethodPut, key, q) {
+		http.Error(w, "Invalid or expired upload URL", http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("Content-Type") != q.Get("ct") {
+		http.Error(w, "Content-Type does not match the signed upload", http.StatusForbidden)
+		return
+	}
+	size, err := strconv.ParseInt(q.Get("size"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+	if r.ContentLength < 0 {
+		http.Error(w, "Content-Length is required", http.StatusLengthRequired)
+		return
+	}
+	if r.ContentLength != size {
+		http.Error(w, "Content-Length does not match the signed upload", http.StatusForbidden)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, size)
+	if err := s.Put(r.Context(), key, body, q.Get("ct")); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to store file", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handler serves a LocalStore under /files/: GET with a SignedURL, and PUT
+// with a PresignPut URL. Like a private bucket, unsigned requests are refused.
+func Handler(store *LocalStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, FilesPrefix)
+
+		if r.Method == http.MethodPut {
+			store.servePut(w, r, key)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !store.verify(http.MethodGet, key, r.URL.Query()) {
+			http.Error(w, "Invalid or expired URL", http.StatusForbidden)
+			return
+		}
+
+		obj, err := store.Open(r.Context(), key)
+		if err == ErrNotFound || err == ErrInvalidKey {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+		defer obj.Body.Close()
+
+		if obj.ContentType != "" {
+			w.Header().Set("Content-Type", obj.ContentType)
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		// Files support range requests; other bodies are streamed as-is
+		if rs, ok := obj.Body.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, path.Base(key), obj.ModTime, rs)
+			return
+		}
+		io.Copy(w, obj.Body)
+	})
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Store keeps objects in a private S3 bucket and hands out presigned URLs
+type S3Store struct {
+	Client *s3.Client
+	Bucket string
+	Region string
+}
+
+func NewS3Store(client *s3.Client, bucket, region string) *S3Store {
+	return &S3Store{Client: client, Bucket: bucket, Region: region}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	input := &s3.PutObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+		Body:   body,
+	}
+	if contentType != "" {
+		input.ContentType = &contentType
+	}
+	_, err := s.Client.PutObject(ctx, input)
+	return err
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+	})
+	return err
+}
+
+func (s *S3Store) URL(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, key)
+}
+
+func (s *S3Store) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	req, err := s3.NewPresignClient(s.Client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (*Object, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Body: out.Body,
+		Info: Info{
+			ContentType: aws.ToString(out.ContentType),
+			Size:        aws.ToInt64(out.ContentLength),
+			ModTime:     aws.ToTime(out.LastModified),
+		},
+	}, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (Info, error) {
+	if !validKey(key) {
+		return Info{}, ErrInvalidKey
+	}
+	out, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &s.Bucket,
+		Key:    &key,
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{
+		ContentType: aws.ToString(out.ContentType),
+		Size:        aws.ToInt64(out.ContentLength),
+		ModTime:     aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string, fn func(key string, info Info) error) error {
+	pages := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket: &s.Bucket,
+		Prefix: &prefix,
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			info := Info{
+				Size:    aws.ToInt64(obj.Size),
+				ModTime: aws.ToTime(obj.LastModified),
+			}
+			if err := fn(aws.ToString(obj.Key), info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PresignPut signs a PUT for the bucket itself. Content-Length is part of
+// the signature, so S3 refuses a body of any other size.
+func (s *S3Store) PresignPut(ctx context.Context, key, contentType string, size int64, ttl time.Duration) (PresignedPut, error) {
+	if !validKey(key) {
+		return PresignedPut{}, ErrInvalidKey
+	}
+	if size <= 0 {
+		return PresignedPut{}, errUploadSize
+	}
+	req, err := s3.NewPresignClient(s.Client).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.Bucket,
+		Key:           &key,
+		ContentType:   &contentType,
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return PresignedPut{}, err
+	}
+	return PresignedPut{
+		URL:       req.URL,
+		Method:    req.Method,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
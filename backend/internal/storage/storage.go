@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/awsclient"
+)
+
+// Backends selectable with STORAGE_BACKEND
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Store is a private blob store addressed by slash-separated keys such as
+// "images/12/<uuid>.png" or "thumbnails/12.png"
+type Store interface {
+	// Put stores body under key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// URL is the canonical, unsigned address of key. The bucket is private, so
+	// clients need SignedURL; URL is for recognising our own links.
+	URL(key string) string
+	// SignedURL returns a URL that can fetch key until ttl runs out
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// Open reads key back; returns ErrNotFound if it does not exist
+	Open(ctx context.Context, key string) (*Object, error)
+	// Stat describes key without reading it; returns ErrNotFound if it does not exist
+	Stat(ctx context.Context, key string) (Info, error)
+	// List calls fn for every object whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(key string, info Info) error) error
+	// PresignPut returns a URL a client can upload key to directly, valid for
+	// ttl. Only a body of exactly size bytes is accepted; still re-check with
+	// Stat, as the URL can be used more than once.
+	PresignPut(ctx context.Context, key, contentType string, size int64, ttl time.Duration) (PresignedPut, error)
+}
+
+// Info describes a stored blob
+type Info struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Object is an opened blob. Callers must close Body.
+type Object struct {
+	Body io.ReadCloser
+	Info
+}
+
+// PresignedPut is a time-limited upload URL. The client must send Headers
+// with its request.
+type PresignedPut struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// NewFromEnv builds the configured store:
+//   - STORAGE_BACKEND=s3 (default when S3_BUCKET is set): S3_BUCKET, AWS_REGION
+//   - STORAGE_BACKEND=local: LOCAL_STORAGE_DIR (default "./uploads") and
+//     PUBLIC_BASE_URL (default "http://localhost:8080"); files are served by
+//     the API under /files/. Upload URLs are signed with STORAGE_SIGNING_KEY
+//     (default: JWT_SECRET).
+func NewFromEnv() (Store, error) {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_BACKEND")))
+	if backend == "" {
+		backend = BackendLocal
+		if os.Getenv("S3_BUCKET") != "" {
+			backend = BackendS3
+		}
+	}
+
+	switch backend {
+	case BackendS3:
+		bucket := os.Getenv("S3_BUCKET")
+		if bucket == "" {
+			return nil, errors.New("S3_BUCKET is required for the s3 storage backend")
+		}
+		return NewS3Store(awsclient.NewS3Client(), bucket, os.Getenv("AWS_REGION")), nil
+
+	case BackendLocal:
+		dir := os.Getenv("LOCAL_STORAGE_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		baseURL := os.Getenv("PUBLIC_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8080"
+		}
+		secret := os.Getenv("STORAGE_SIGNING_KEY")
+		if secret == "" {
+			secret = os.Getenv("JWT_SECRET")
+		}
+		return NewLocalStore(dir, baseURL, []byte(secret))
+
+	default:
+		return nil, errors.New("unknown STORAGE_BACKEND " + backend + " (must be 's3' or 'local')")
+	}
+}
+
+// KeyFromURL recovers the key from a key, or from a URL built by a Store's URL
+// or SignedURL method. prefix is the key's top-level folder, e.g. "images/".
+// Returns false when the URL does not contain one.
+func KeyFromURL(url, prefix string) (string, bool) {
+	if i := strings.IndexByte(url, '?'); i != -1 {
+		url = url[:i]
+	}
+	idx := strings.LastIndex(url, prefix)
+	if idx == -1 {
+		return "", false
+	}
+	return url[idx:], true
+}
+
+// validKey rejects keys that could escape the store's root
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
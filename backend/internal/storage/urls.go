@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// URLTTL is how long the signed URLs handed to clients stay valid
+const URLTTL = 15 * time.Minute
+
+var signer Store
+
+// SetSigner registers the store whose keys Sign and Normalize understand
+func SetSigner(s Store) {
+	signer = s
+}
+
+// IsKey reports whether ref is a bare object key rather than a URL
+func IsKey(ref string) bool {
+	return !strings.Contains(ref, "://") && validKey(ref)
+}
+
+// Sign is SignWith for the registered store
+func Sign(ref string) string {
+	return SignWith(context.Background(), signer, ref)
+}
+
+// SignWith turns a stored reference into something a client can fetch: keys
+// (and stale links into store) become fresh signed URLs, external URLs are
+// returned unchanged.
+func SignWith(ctx context.Context, store Store, ref string) string {
+	if ref == "" || store == nil {
+		return ref
+	}
+	key := NormalizeWith(store, ref)
+	if !IsKey(key) {
+		return ref
+	}
+	url, err := store.SignedURL(ctx, key, URLTTL)
+	if err != nil {
+		log.Printf("WARN: failed to sign %s: %v", key, err)
+		return ""
+	}
+	return url
+}
+
+// Normalize is NormalizeWith for the registered store
+func Normalize(ref string) string {
+	return NormalizeWith(signer, ref)
+}
+
+// NormalizeWith turns a URL pointing into store, signed or not, back into its
+// bare key. Anything else is returned unchanged.
+func NormalizeWith(store Store, ref string) string {
+	if store == nil || IsKey(ref) {
+		return ref
+	}
+	base := store.URL("")
+	if !strings.HasPrefix(ref, base) {
+		return ref
+	}
+	key := strings.TrimPrefix(ref, base)
+	if i := strings.IndexByte(key, '?'); i != -1 {
+		key = key[:i]
+	}
+	if !validKey(key) {
+		return ref
+	}
+	return key
+}
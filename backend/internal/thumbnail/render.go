@@ -0,0 +1,53 @@
+// Package thumbnail draws board previews on the server, so boards edited
+// through share links or the API get one without a browser uploading it.
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"math"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/export"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+	"github.com/disintegration/imaging"
+)
+
+// Output size; the dashboard shows previews at 4:3
+const (
+	Width  = 640
+	Height = 480
+)
+
+// maxCanvas is the longest side drawn before the final downscale
+const maxCanvas = 4096
+
+// Render draws the whole board, framed at 4:3, and returns a PNG. Images use
+// their smallest stored variants.
+func Render(ctx context.Context, store storage.Store, cards []card.Card, links []card.Link) ([]byte, error) {
+	layout := export.NewLayout(ctx, store, cards, links, export.Options{SmallImages: true})
+	layout.Bounds = fitAspect(layout.Bounds)
+
+	// Draw at board scale (or less, for huge boards), then shrink once
+	scale := math.Min(1, float64(maxCanvas)/float64(layout.Bounds.Dx()))
+	canvas := export.Draw(layout, scale)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, imaging.Resize(canvas, Width, Height, imaging.Lanczos)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fitAspect grows r to the output's aspect ratio around its centre
+func fitAspect(r image.Rectangle) image.Rectangle {
+	w, h := r.Dx(), r.Dy()
+	if w*Height > h*Width {
+		grow := w*Height/Width - h
+		return image.Rect(r.Min.X, r.Min.Y-grow/2, r.Max.X, r.Max.Y+grow-grow/2)
+	}
+	grow := h*Width/Height - w
+	return image.Rect(r.Min.X-grow/2, r.Min.Y, r.Max.X+grow-grow/2, r.Max.Y)
+}
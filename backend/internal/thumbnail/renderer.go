@@ -0,0 +1,98 @@
+package thumbnail
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LoganTackett1/brainstorming-backend/internal/board"
+	"github.com/LoganTackett1/brainstorming-backend/internal/card"
+	"github.com/LoganTackett1/brainstorming-backend/internal/imageproc"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
+	"github.com/LoganTackett1/brainstorming-backend/internal/storage"
+)
+
+// DefaultSettle is how long a board has to be left alone before its
+// thumbnail is redrawn
+const DefaultSettle = 30 * time.Second
+
+// Renderer redraws a board's thumbnail once card changes on it settle. It
+// implements card.Publisher; register it alongside the realtime hub.
+type Renderer struct {
+	DB     *sql.DB
+	Store  storage.Store
+	Settle time.Duration
+
+	mu     sync.Mutex
+	timers map[int64]*time.Timer
+	render sync.Mutex // one board at a time; rendering is CPU-heavy
+}
+
+func NewRenderer(db *sql.DB, store storage.Store, settle time.Duration) *Renderer {
+	return &Renderer{
+		DB:     db,
+		Store:  store,
+		Settle: settle,
+		timers: make(map[int64]*time.Timer),
+	}
+}
+
+// Publish implements card.Publisher
+func (r *Renderer) Publish(ev card.Event) {
+	r.Schedule(ev.BoardID)
+}
+
+// Schedule redraws boardID's thumbnail after Settle, restarting the wait if
+// one is already pending
+func (r *Renderer) Schedule(boardID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.timers[boardID]; ok {
+		t.Reset(r.Settle)
+		return
+	}
+	r.timers[boardID] = time.AfterFunc(r.Settle, func() {
+		r.mu.Lock()
+		delete(r.timers, boardID)
+		r.mu.Unlock()
+
+		if err := r.Regenerate(context.Background(), boardID); err != nil {
+			log.Printf("thumbnail: board %d: %v", boardID, err)
+		}
+	})
+}
+
+// Regenerate draws boardID's thumbnail now and stores it through the same
+// key scheme as uploaded thumbnails
+func (r *Renderer) Regenerate(ctx context.Context, boardID int64) error {
+	r.render.Lock()
+	defer r.render.Unlock()
+
+	cards, err := card.GetCardsByBoard(r.DB, boardID)
+	if err != nil {
+		return err
+	}
+	links, err := card.GetLinksByBoard(r.DB, boardID)
+	if err != nil {
+		return err
+	}
+	data, err := Render(ctx, r.Store, cards, links)
+	if err != nil {
+		return err
+	}
+
+	_, err = board.StoreThumbnail(ctx, r.DB, r.Store, boardID, imageproc.Image{
+		Data:        data,
+		ContentType: "image/png",
+		Ext:         ".png",
+		Width:       Width,
+		Height:      Height,
+	})
+	if err == sql.ErrNoRows || err == quota.ErrQuotaExceeded {
+		return nil // board deleted meanwhile, or no room for a preview
+	}
+	return err
+}
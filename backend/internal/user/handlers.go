@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/LoganTackett1/brainstorming-backend/internal/middleware"
+	"github.com/LoganTackett1/brainstorming-backend/internal/quota"
 )
 
 type SignupHandler struct {
@@ -20,6 +21,10 @@ type MeHandler struct {
 	DB *sql.DB
 }
 
+type UsageHandler struct {
+	DB *sql.DB
+}
+
 type EmailHandler struct {
 	DB *sql.DB
 }
@@ -67,6 +72,31 @@ func (h *MeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GET /me/usage
+// Boards and cards the user owns and the storage they take against the quota,
+// plus what gc will give back from images no longer on any card
+func (h *UsageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := GetUserID(r)
+	if userID == 0 {
+		middleware.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	usage, err := quota.ForUser(h.DB, userID)
+	if err != nil {
+		middleware.JSONError(w, "Failed to fetch usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
 func (h *SignupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		middleware.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -17,14 +17,12 @@ const userIDKey contextKey = "userID"
 // AuthMiddleware checks for JWT and extracts the user ID
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		tokenString, ok := bearerToken(r)
+		if !ok {
 			middleware.JSONError(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
 		secret := []byte(os.Getenv("JWT_SECRET"))
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			// Ensure signing method is HMAC
@@ -58,6 +56,21 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// bearerToken reads the JWT from the Authorization header. Browsers cannot set
+// headers on a WebSocket handshake, so upgrade requests may pass ?token= instead.
+func bearerToken(r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer "), true
+	}
+	if authHeader == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		if token := r.URL.Query().Get("token"); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
 // GetUserID extracts userID from context
 func GetUserID(r *http.Request) int64 {
 	if val := r.Context().Value(userIDKey); val != nil {
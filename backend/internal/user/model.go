@@ -44,3 +44,12 @@ func GetUserIDByEmail(db *sql.DB, email string) (int64, error) {
 	}
 	return id, nil
 }
+
+func GetEmailByUserID(db *sql.DB, userID int64) (string, error) {
+	var email string
+	err := db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&email)
+	if err != nil {
+		return "", err
+	}
+	return email, nil
+}